@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"mdns-browser/internal/audit"
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/discovery"
+	"mdns-browser/internal/inventory"
+	"mdns-browser/internal/query"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// runAudit browses the local link for all service types of the catalogue
+// and prints a report of potentially sensitive exposure. Interrupting the
+// audit reports the services found so far.
+func runAudit(args []string) {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	duration := fs.Duration("duration", 0, "browse for this long (default: one full sweep)")
+	output := fs.String("output", "text", "print the report as `format` ("+strings.Join(audit.Formats, ", ")+")")
+	filter := fs.String("filter", "", "only audit services matching `query`")
+	_ = fs.Parse(args)
+
+	if !slices.Contains(audit.Formats, *output) {
+		fmt.Printf("unknown output format %q\n", *output)
+		os.Exit(2)
+	}
+	q, err := query.Parse(*filter)
+	if err != nil {
+		fmt.Println("Error parsing --filter:", err)
+		os.Exit(2)
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	if *duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	started := time.Now()
+	slog.Info("browsing for services", "types", len(discovery.Services), "duration", *duration)
+	addCh := make(chan data.ListItem, 10)
+	go func() {
+		defer close(addCh)
+		var err error
+		if *duration > 0 {
+			err = discovery.WatchServices(ctx, 0, addCh)
+		} else {
+			err = discovery.ListAllServices(ctx, addCh)
+		}
+		if err != nil && ctx.Err() == nil {
+			slog.Error("error discovering services", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	c := cache.New()
+	for it := range filterServices(addCh, q) {
+		c.Put(it)
+	}
+	items := c.Items()
+
+	report := audit.Report{
+		Started:  started,
+		Duration: time.Since(started),
+		Services: len(items),
+		Hosts:    len(inventory.Hosts(items)),
+		Findings: audit.Audit(items),
+	}
+	if err := audit.Write(os.Stdout, *output, report); err != nil {
+		fmt.Println("Error writing report:", err)
+		os.Exit(1)
+	}
+}
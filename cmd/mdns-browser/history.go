@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"mdns-browser/internal/history"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// showHistory prints the recorded sightings of services from the history
+// file.
+func showHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	name := fs.String("name", "", "show services whose name matches the glob `pattern`, e.g. 'HP*'")
+	file := fs.String("file", history.DefaultPath(), "history `file`")
+	gap := fs.Duration("gap", history.DefaultGap, "consider a service offline after not seeing it for this long")
+	output := fs.String("output", "text", "print the history as text or json")
+	_ = fs.Parse(args)
+
+	if *name == "" {
+		fmt.Println("history requires --name")
+		os.Exit(2)
+	}
+
+	sightings, err := history.Query(*file, *name)
+	if err != nil {
+		fmt.Println("Error reading history:", err)
+		os.Exit(1)
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if sightings == nil {
+			sightings = []history.Sighting{}
+		}
+		_ = enc.Encode(sightings)
+		return
+	}
+
+	if len(sightings) == 0 {
+		fmt.Printf("No sightings of %s in %s\n", *name, *file)
+		return
+	}
+
+	// group the sightings by service, in order of the first sighting
+	var names []string
+	byName := make(map[string][]history.Sighting)
+	for _, s := range sightings {
+		key := strings.ToLower(s.Name)
+		if _, ok := byName[key]; !ok {
+			names = append(names, s.Name)
+		}
+		byName[key] = append(byName[key], s)
+	}
+
+	for n, svc := range names {
+		if n > 0 {
+			fmt.Println()
+		}
+		ss := byName[strings.ToLower(svc)]
+		intervals := history.Presence(ss, *gap)
+		last := ss[len(ss)-1]
+		fmt.Println(svc)
+		fmt.Printf("  first seen  %s\n", intervals[0].Start.Local().Format(time.DateTime))
+		fmt.Printf("  last seen   %s (%s ago)\n", last.Time.Local().Format(time.DateTime), time.Since(last.Time).Round(time.Second))
+		fmt.Printf("  last host   %s\n", strings.Join(slices.DeleteFunc([]string{last.Host, last.AddrV4, last.AddrV6}, func(s string) bool { return s == "" }), " "))
+		fmt.Printf("  sightings   %d\n", len(ss))
+		changes := 0
+		for i := 1; i < len(ss); i++ {
+			if ss[i].TXTHash != ss[i-1].TXTHash {
+				changes++
+			}
+		}
+		fmt.Printf("  TXT changes %d\n", changes)
+		fmt.Println("  online")
+		slices.Reverse(intervals)
+		for _, i := range intervals {
+			fmt.Printf("    %s – %s  %-10s %s\n",
+				i.Start.Local().Format(time.DateTime), i.End.Local().Format(time.DateTime),
+				i.End.Sub(i.Start).Round(time.Second), i.Interface)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"mdns-browser/internal/analyzer"
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/discovery"
+	"mdns-browser/internal/query"
+	"os"
+	"time"
+)
+
+// watch keeps browsing the local link and returns a cache of the services
+// currently visible that match q. Services not seen for expire are removed.
+func watch(ctx context.Context, interval, expire time.Duration, q query.Query) *cache.Cache {
+	addCh := make(chan data.ListItem, 10)
+	c := cache.New()
+	go c.Run(ctx, analyzeServices(filterServices(addCh, q), analyzer.New()), expire)
+	go func() {
+		err := discovery.WatchServices(ctx, interval, addCh)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("error discovering services", "error", err)
+			os.Exit(1)
+		}
+	}()
+	return c
+}
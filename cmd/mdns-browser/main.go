@@ -2,22 +2,37 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
+	"mdns-browser/internal/actions"
+	"mdns-browser/internal/analyzer"
+	"mdns-browser/internal/api"
+	"mdns-browser/internal/cache"
 	"mdns-browser/internal/data"
 	"mdns-browser/internal/discovery"
+	"mdns-browser/internal/export"
+	"mdns-browser/internal/filesd"
+	"mdns-browser/internal/health"
+	"mdns-browser/internal/history"
+	"mdns-browser/internal/hooks"
+	"mdns-browser/internal/metrics"
+	"mdns-browser/internal/query"
+	"mdns-browser/internal/traffic"
 	"mdns-browser/internal/tui"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-func main() {
-	addCh := make(chan data.ListItem, 10)
+// signalContext returns a context that is cancelled on SIGINT or SIGTERM.
+func signalContext() (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -28,17 +43,225 @@ func main() {
 		cancel()
 	}()
 
-	go func() {
-		err := discovery.ListAllServices(ctx, addCh)
+	return ctx, cancel
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve-dns":
+			serveDNS(os.Args[2:])
+			return
+		case "web":
+			serveWeb(os.Args[2:])
+			return
+		case "history":
+			showHistory(os.Args[2:])
+			return
+		case "audit":
+			runAudit(os.Args[2:])
+			return
+		}
+	}
+	browse(os.Args[1:])
+}
+
+func browse(args []string) {
+	fs := flag.NewFlagSet("mdns-browser", flag.ExitOnError)
+	var domains []string
+	fs.Func("domain", "browse `domain` via unicast DNS-SD in addition to local. (repeatable)", func(s string) error {
+		domains = append(domains, s)
+		return nil
+	})
+	dnsServer := fs.String("dns-server", "", "DNS server `host:port` for unicast DNS-SD (default from /etc/resolv.conf)")
+	output := fs.String("output", "", "print services in `format` ("+strings.Join(export.Formats, ", ")+") instead of starting the TUI")
+	origin := fs.String("origin", "local.", "`origin` of exported zone files")
+	timeout := fs.Duration("timeout", 0, "stop browsing after this long when using --output (default: one full sweep)")
+	inspectTLS := fs.Bool("inspect-tls", false, "inspect the certificates of TLS services when using --output")
+	httpAddr := fs.String("http", "", "serve the HTTP API on `address`, e.g. :8080")
+	var origins []string
+	fs.Func("allow-origin", "also accept WebSocket clients of --http from `origin`, e.g. https://dash.example (repeatable)", func(s string) error {
+		origins = append(origins, s)
+		return nil
+	})
+	fileSD := fs.String("file-sd", "", "keep a Prometheus file_sd JSON `file` of the discovered services up to date")
+	fileSDConfig := fs.String("file-sd-config", "", "JSON `file` selecting and mapping service types for --file-sd")
+	actionsConfig := fs.String("actions", actions.DefaultConfigPath(), "JSON `file` with launch actions per service type")
+	hooksConfig := fs.String("hooks", "", "JSON `file` with hooks to run on service events")
+	historyFile := fs.String("history", "", "record sightings of services in `file`, e.g. "+history.DefaultPath())
+	retention := fs.Duration("history-retention", history.DefaultRetention, "drop sightings older than this from --history, 0 keeps all")
+	noTUI := fs.Bool("no-tui", false, "do not start the TUI, only run --http, --file-sd and --hooks")
+	filter := fs.String("filter", "", "only browse services matching `query`, e.g. 'type:_ipp port:631'")
+	interval := fs.Duration("interval", time.Minute, "pause between discovery sweeps, unless printing one sweep with --output")
+	expire := fs.Duration("expire", 30*time.Minute, "remove services not seen for this long with --http, --file-sd or --hooks")
+	_ = fs.Parse(args)
+
+	// live mode keeps browsing and maintains a cache of the current services
+	live := (*httpAddr != "" || *fileSD != "" || *hooksConfig != "") && *output == ""
+	if *noTUI && !live {
+		fmt.Println("--no-tui requires --http, --file-sd or --hooks")
+		os.Exit(2)
+	}
+
+	q, err := query.Parse(*filter)
+	if err != nil {
+		fmt.Println("Error parsing --filter:", err)
+		os.Exit(2)
+	}
+
+	if live && *expire <= 0 {
+		fmt.Println("--expire must be positive")
+		os.Exit(2)
+	}
+
+	actionsCfg, err := actions.LoadConfig(*actionsConfig)
+	if err != nil {
+		fmt.Println("Error loading actions:", err)
+		os.Exit(1)
+	}
+
+	var hooksCfg hooks.Config
+	if *hooksConfig != "" {
+		var err error
+		if hooksCfg, err = hooks.LoadConfig(*hooksConfig); err != nil {
+			fmt.Println("Error loading hooks:", err)
+			os.Exit(1)
+		}
+	}
+
+	var sdConfig filesd.Config
+	if *fileSDConfig != "" {
+		var err error
+		if sdConfig, err = filesd.LoadConfig(*fileSDConfig); err != nil {
+			fmt.Println("Error loading file_sd config:", err)
+			os.Exit(1)
+		}
+	}
+
+	var store *history.Store
+	if *historyFile != "" {
+		if store, err = history.Open(*historyFile, *retention); err != nil {
+			fmt.Println("Error opening history:", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+	}
+
+	addCh := make(chan data.ListItem, 10)
+	ctx, cancel := signalContext()
+	defer cancel()
+	if *output != "" && *timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if len(domains) > 0 && *dnsServer == "" {
+		server, err := discovery.DefaultDNSServer()
 		if err != nil {
+			fmt.Println("Error finding DNS server:", err)
+			os.Exit(1)
+		}
+		*dnsServer = server
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		var err error
+		// the TUI and live mode keep sweeping, --output prints one sweep
+		if *output == "" {
+			err = discovery.WatchServices(ctx, *interval, addCh)
+		} else {
+			err = discovery.ListAllServices(ctx, addCh)
+		}
+		if err != nil && ctx.Err() == nil {
 			slog.Error("error discovering services", "error", err)
 			os.Exit(1)
 		}
+	})
+	if len(domains) > 0 {
+		wg.Go(func() {
+			for {
+				err := discovery.ListUnicastServices(ctx, discovery.UnicastOpts{
+					Domains: domains,
+					Server:  *dnsServer,
+				}, addCh)
+				if err != nil && ctx.Err() == nil {
+					slog.Error("error browsing unicast DNS-SD", "error", err)
+				}
+				if *output != "" {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(*interval):
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(addCh)
 	}()
+	services := analyzeServices(filterServices(addCh, q), analyzer.New())
+	if store != nil {
+		services = recordServices(services, store)
+	}
+
+	if *output != "" {
+		printServices(services, *output, *inspectTLS, export.Options{Origin: *origin, FileSD: sdConfig})
+		return
+	}
+
+	tuiCh := services
+	if live {
+		cacheCh := services
+		if !*noTUI {
+			// the TUI sees every response, the cache only the changes
+			cacheCh, tuiCh = tee(services)
+		}
+		c := cache.New()
+		go c.Run(ctx, cacheCh, *expire)
+
+		if *httpAddr != "" {
+			m := metrics.New(c)
+			go m.Run(ctx)
+
+			srv := &api.Server{Cache: c, Metrics: m, AllowedOrigins: origins}
+			go func() {
+				if err := srv.ListenAndServe(ctx, *httpAddr); err != nil {
+					slog.Error("error serving HTTP API", "error", err)
+					os.Exit(1)
+				}
+			}()
+		}
+		if *fileSD != "" {
+			go func() {
+				if err := filesd.Run(ctx, c, *fileSD, sdConfig); err != nil {
+					slog.Error("error writing file_sd targets", "error", err)
+					os.Exit(1)
+				}
+			}()
+		}
+
+		if *hooksConfig != "" {
+			go hooks.Run(ctx, c, hooksCfg)
+		}
+
+		if *noTUI {
+			slog.Info("running without TUI", "http", *httpAddr, "file-sd", *fileSD, "hooks", *hooksConfig)
+			<-ctx.Done()
+			return
+		}
+	}
 
 	m := tui.Tui(tui.ListOpts{
-		Title: "Found Services",
-		AddCh: addCh,
+		Title:        "Found Services",
+		AddCh:        tuiCh,
+		ExportOrigin: *origin,
+		Actions:      actionsCfg,
+		History:      store,
+		Packets:      listenTraffic(ctx),
 	})
 
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx))
@@ -48,3 +271,111 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// listenTraffic decodes the mDNS packets on the local link for the traffic
+// tab. Listen errors are shown in the tab as a packet that could not be
+// decoded.
+func listenTraffic(ctx context.Context) <-chan traffic.Packet {
+	ch := make(chan traffic.Packet, 100)
+	go func() {
+		if err := traffic.Listen(ctx, ch); err != nil {
+			select {
+			case ch <- traffic.Packet{Time: time.Now(), Err: "error listening: " + err.Error()}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ch
+}
+
+// filterServices forwards the services matching q to the returned channel,
+// which is closed once in is closed.
+func filterServices(in chan data.ListItem, q query.Query) chan data.ListItem {
+	out := make(chan data.ListItem, cap(in))
+	go func() {
+		defer close(out)
+		for it := range in {
+			if q.Match(it) {
+				out <- it
+			}
+		}
+	}()
+	return out
+}
+
+// analyzeServices adds the warnings of the analyzer to every service from
+// in and forwards it to the returned channel, which is closed once in is
+// closed.
+func analyzeServices(in chan data.ListItem, a *analyzer.Analyzer) chan data.ListItem {
+	out := make(chan data.ListItem, cap(in))
+	go func() {
+		defer close(out)
+		for it := range in {
+			out <- a.Analyze(it)
+		}
+	}()
+	return out
+}
+
+// recordServices records every service from in in the history store and
+// forwards it to the returned channel, which is closed once in is closed.
+func recordServices(in chan data.ListItem, store *history.Store) chan data.ListItem {
+	out := make(chan data.ListItem, cap(in))
+	go func() {
+		defer close(out)
+		for it := range in {
+			if err := store.Record(it, it.LastSeen); err != nil {
+				slog.Error("error recording history", "error", err)
+			}
+			out <- it
+		}
+	}()
+	return out
+}
+
+// tee forwards every service from in to both returned channels, which are
+// closed once in is closed. Repeated sightings of a service are dropped for
+// the second channel when its buffer is full, so that a busy TUI does not
+// hold up the cache, but the first sighting of every service is delivered.
+func tee(in chan data.ListItem) (chan data.ListItem, chan data.ListItem) {
+	a := make(chan data.ListItem, cap(in))
+	b := make(chan data.ListItem, 256)
+	go func() {
+		defer close(a)
+		defer close(b)
+		delivered := make(map[string]bool)
+		for it := range in {
+			a <- it
+			if !delivered[it.ID()] {
+				b <- it
+				delivered[it.ID()] = true
+				continue
+			}
+			select {
+			case b <- it:
+			default:
+			}
+		}
+	}()
+	return a, b
+}
+
+// printServices collects services until addCh is closed and prints them.
+func printServices(addCh chan data.ListItem, format string, inspectTLS bool, opts export.Options) {
+	c := cache.New()
+	for it := range addCh {
+		c.Put(it)
+	}
+	items := c.Items()
+	if inspectTLS {
+		for i, it := range items {
+			if health.IsSecure(it.Service) && it.Port > 0 {
+				items[i].Certificate = health.InspectCertificate(context.Background(), it)
+			}
+		}
+	}
+	if err := export.Write(os.Stdout, format, items, opts); err != nil {
+		fmt.Println("Error writing services:", err)
+		os.Exit(1)
+	}
+}
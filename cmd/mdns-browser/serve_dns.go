@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"mdns-browser/internal/dnsproxy"
+	"mdns-browser/internal/query"
+	"os"
+	"time"
+)
+
+// serveDNS runs a unicast DNS-SD gateway for the services discovered on
+// the local link.
+func serveDNS(args []string) {
+	fs := flag.NewFlagSet("serve-dns", flag.ExitOnError)
+	listen := fs.String("listen", ":5300", "`address` to answer DNS queries on")
+	domain := fs.String("domain", "", "`domain` to publish local services under, e.g. lab.example.")
+	interval := fs.Duration("interval", time.Minute, "pause between discovery sweeps")
+	expire := fs.Duration("expire", 30*time.Minute, "remove services not seen for this long")
+	filter := fs.String("filter", "", "only serve services matching `query`")
+	_ = fs.Parse(args)
+
+	q, err := query.Parse(*filter)
+	if err != nil {
+		slog.Error("error parsing --filter", "error", err)
+		os.Exit(2)
+	}
+
+	if *expire <= 0 {
+		slog.Error("--expire must be positive")
+		os.Exit(2)
+	}
+
+	if *domain == "" {
+		slog.Error("serve-dns requires --domain")
+		os.Exit(2)
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	srv := &dnsproxy.Server{Addr: *listen, Domain: *domain, Cache: watch(ctx, *interval, *expire, q)}
+	slog.Info("serving DNS-SD", "listen", *listen, "domain", *domain)
+	if err := srv.ListenAndServe(ctx); err != nil {
+		slog.Error("error serving DNS", "error", err)
+		os.Exit(1)
+	}
+}
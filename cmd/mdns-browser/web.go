@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"mdns-browser/internal/metrics"
+	"mdns-browser/internal/query"
+	"mdns-browser/internal/web"
+	"os"
+	"time"
+)
+
+// serveWeb serves the browser based UI for the services discovered on the
+// local link.
+func serveWeb(args []string) {
+	fs := flag.NewFlagSet("web", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "`address` to serve the web UI on")
+	interval := fs.Duration("interval", time.Minute, "pause between discovery sweeps")
+	expire := fs.Duration("expire", 30*time.Minute, "remove services not seen for this long")
+	filter := fs.String("filter", "", "only serve services matching `query`")
+	var origins []string
+	fs.Func("allow-origin", "also accept WebSocket clients from `origin`, e.g. https://dash.example (repeatable)", func(s string) error {
+		origins = append(origins, s)
+		return nil
+	})
+	_ = fs.Parse(args)
+
+	q, err := query.Parse(*filter)
+	if err != nil {
+		slog.Error("error parsing --filter", "error", err)
+		os.Exit(2)
+	}
+
+	if *expire <= 0 {
+		slog.Error("--expire must be positive")
+		os.Exit(2)
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	c := watch(ctx, *interval, *expire, q)
+	m := metrics.New(c)
+	go m.Run(ctx)
+
+	srv := &web.Server{Cache: c, Metrics: m, AllowedOrigins: origins}
+	slog.Info("serving web UI", "listen", *listen)
+	if err := srv.ListenAndServe(ctx, *listen); err != nil {
+		slog.Error("error serving web UI", "error", err)
+		os.Exit(1)
+	}
+}
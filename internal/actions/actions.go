@@ -0,0 +1,221 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mdns-browser/internal/data"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Action launches something for a service. URL is opened with the system
+// URL handler, Command is run in the terminal. Both are text/template
+// strings executed with a Service.
+type Action struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	URL     string   `json:"url,omitempty"`
+	Command []string `json:"command,omitempty"`
+}
+
+// Config is the actions configuration file. Actions configured for a
+// service type replace the defaults of that type.
+type Config struct {
+	Actions []Action `json:"actions"`
+}
+
+// Defaults are the built-in actions.
+var Defaults = []Action{
+	{Name: "Open in browser", Type: "_http._tcp", URL: "http://{{.URLHost}}:{{.Port}}{{.Path}}"},
+	{Name: "Open in browser", Type: "_https._tcp", URL: "https://{{.URLHost}}:{{.Port}}{{.Path}}"},
+	{Name: "SSH", Type: "_ssh._tcp", Command: []string{"ssh", "-p", "{{.Port}}", "--", "{{with .TXT \"u\"}}{{.}}@{{end}}{{.Host}}"}},
+	{Name: "SSH", Type: "_sftp-ssh._tcp", Command: []string{"ssh", "-p", "{{.Port}}", "--", "{{with .TXT \"u\"}}{{.}}@{{end}}{{.Host}}"}},
+	{Name: "SFTP", Type: "_sftp-ssh._tcp", Command: []string{"sftp", "-P", "{{.Port}}", "--", "{{with .TXT \"u\"}}{{.}}@{{end}}{{.Host}}"}},
+	{Name: "Open VNC viewer", Type: "_rfb._tcp", URL: "vnc://{{.URLHost}}:{{.Port}}"},
+	{Name: "Open file share", Type: "_smb._tcp", URL: "smb://{{.URLHost}}/"},
+	{Name: "Open file share", Type: "_afpovertcp._tcp", URL: "afp://{{.URLHost}}/"},
+}
+
+// DefaultConfigPath returns the location of the actions config file.
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mdns-browser", "actions.json")
+}
+
+// LoadConfig reads a JSON actions config. A missing file is not an error.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path == "" {
+		return cfg, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	for n, a := range cfg.Actions {
+		if (a.URL == "") == (len(a.Command) == 0) {
+			return cfg, fmt.Errorf("action %d: exactly one of url and command is required", n)
+		}
+		for _, t := range append([]string{a.URL}, a.Command...) {
+			if _, err := template.New("").Parse(t); err != nil {
+				return cfg, fmt.Errorf("action %d: %w", n, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// For returns the actions available for a service.
+func (cfg Config) For(it data.ListItem) []Action {
+	var configured, defaults []Action
+	for _, a := range cfg.Actions {
+		if strings.EqualFold(strings.TrimSuffix(a.Type, "."), it.Service) {
+			configured = append(configured, a)
+		}
+	}
+	if len(configured) > 0 {
+		return configured
+	}
+	for _, a := range Defaults {
+		if strings.EqualFold(a.Type, it.Service) {
+			defaults = append(defaults, a)
+		}
+	}
+	return defaults
+}
+
+// Service is the data available to action templates.
+type Service struct {
+	data.ListItem
+}
+
+// Host returns the host name without trailing dot, or an address if the
+// service has no host name, as taken by commands such as ssh.
+func (s Service) Host() string {
+	if host := strings.TrimSuffix(s.ListItem.Host, "."); host != "" {
+		return host
+	}
+	return s.Addr()
+}
+
+// URLHost returns Host for use in a URL: IPv6 addresses are bracketed and
+// their zone escaped as in RFC 6874.
+func (s Service) URLHost() string {
+	host := s.Host()
+	if strings.Contains(host, ":") {
+		return "[" + strings.ReplaceAll(host, "%", "%25") + "]"
+	}
+	return host
+}
+
+// Addr returns the IPv4 address, or the IPv6 address with its zone.
+func (s Service) Addr() string {
+	if s.AddrV4 != "" {
+		return s.AddrV4
+	}
+	return s.AddrV6
+}
+
+// TXT returns the value of a TXT key.
+func (s Service) TXT(key string) string {
+	for _, field := range s.InfoFields {
+		k, v, _ := strings.Cut(field, "=")
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Path returns the TXT path with a leading slash, as used by _http.
+func (s Service) Path() string {
+	p := s.TXT("path")
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return p
+}
+
+func expand(tmpl string, it data.ListItem) (string, error) {
+	t, err := template.New("").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, Service{it}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ExpandURL expands the URL template of the action.
+func (a Action) ExpandURL(it data.ListItem) (string, error) {
+	return expand(a.URL, it)
+}
+
+// Cmd expands the command template of the action. For URL actions it
+// returns the command that opens the URL with the system handler.
+func (a Action) Cmd(it data.ListItem) (*exec.Cmd, error) {
+	if a.URL != "" {
+		url, err := a.ExpandURL(it)
+		if err != nil {
+			return nil, err
+		}
+		return openCommand(url), nil
+	}
+
+	args := make([]string, len(a.Command))
+	for i, t := range a.Command {
+		arg, err := expand(t, it)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(t, "{{") {
+			if err := checkArg(arg); err != nil {
+				return nil, err
+			}
+		}
+		args[i] = arg
+	}
+	return exec.Command(args[0], args[1:]...), nil
+}
+
+// checkArg rejects an argument rendered from service data that could be
+// taken as an option or split into several arguments.
+func checkArg(arg string) error {
+	if strings.HasPrefix(arg, "-") {
+		return fmt.Errorf("refusing argument %q: starts with -", arg)
+	}
+	if strings.ContainsFunc(arg, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) {
+		return fmt.Errorf("refusing argument %q: contains whitespace or control characters", arg)
+	}
+	return nil
+}
+
+// openCommand returns the platform command that opens a URL.
+func openCommand(url string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	}
+	return exec.Command("xdg-open", url)
+}
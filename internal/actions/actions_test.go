@@ -0,0 +1,27 @@
+package actions
+
+import (
+	"mdns-browser/internal/data"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		it            data.ListItem
+		host, urlHost string
+	}{
+		{data.ListItem{Host: "pi.local.", AddrV6: "fe80::1%eth0"}, "pi.local", "pi.local"},
+		{data.ListItem{AddrV4: "192.168.1.2", AddrV6: "fe80::1%eth0"}, "192.168.1.2", "192.168.1.2"},
+		{data.ListItem{AddrV6: "fe80::1%eth0"}, "fe80::1%eth0", "[fe80::1%25eth0]"},
+		{data.ListItem{AddrV6: "2001:db8::1"}, "2001:db8::1", "[2001:db8::1]"},
+	}
+	for _, tt := range tests {
+		s := Service{tt.it}
+		if got := s.Host(); got != tt.host {
+			t.Errorf("Host() = %q, want %q", got, tt.host)
+		}
+		if got := s.URLHost(); got != tt.urlHost {
+			t.Errorf("URLHost() = %q, want %q", got, tt.urlHost)
+		}
+	}
+}
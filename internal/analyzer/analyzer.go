@@ -0,0 +1,189 @@
+// Package analyzer flags suspicious services in the discovery stream:
+// name conflicts, hosts answering with changing addresses, flapping TXT
+// records and responses from addresses outside the local networks, all of
+// which may be signs of misconfiguration or spoofing.
+package analyzer
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// window is how long sightings are remembered when looking for
+	// conflicts. Hosts that were renamed or changed their address longer
+	// ago are not flagged.
+	window = 10 * time.Minute
+
+	// flapChanges TXT record changes within flapWindow count as flapping.
+	// Discovery sees a service about once per sweep, and a sweep of all
+	// service types takes about ten minutes plus --interval, so the window
+	// spans several sweeps.
+	flapChanges = 3
+	flapWindow  = time.Hour
+)
+
+// sighting is a value seen at a time
+type sighting struct {
+	value string
+	time  time.Time
+}
+
+// observe adds value seen at t to sightings and drops sightings that are
+// older than window.
+func observe(sightings []sighting, value string, t time.Time) []sighting {
+	sightings = slices.DeleteFunc(sightings, func(s sighting) bool {
+		return s.value == value || t.Sub(s.time) > window
+	})
+	return append(sightings, sighting{value: value, time: t})
+}
+
+// values returns the distinct values of sightings, sorted so that
+// warnings do not change with the order of sightings.
+func values(sightings []sighting) []string {
+	var vs []string
+	for _, s := range sightings {
+		vs = append(vs, s.value)
+	}
+	slices.Sort(vs)
+	return vs
+}
+
+// txtState tracks the changes of the TXT record of a service
+type txtState struct {
+	fields  []string
+	changes []time.Time
+	seen    time.Time
+}
+
+// Analyzer remembers recent sightings of services to flag suspicious ones.
+// It is not safe for concurrent use.
+type Analyzer struct {
+	hosts map[string][]sighting // hosts answering for each service ID
+	addrs map[string][]sighting // IPv4 addresses of each host on each interface
+	txt   map[string]txtState   // TXT records by service ID
+
+	pruned time.Time // when services not seen within window were last forgotten
+}
+
+// New returns an Analyzer that has not seen any services.
+func New() *Analyzer {
+	return &Analyzer{
+		hosts: make(map[string][]sighting),
+		addrs: make(map[string][]sighting),
+		txt:   make(map[string]txtState),
+	}
+}
+
+// Analyze records a sighting of it and returns it with warnings about
+// anything suspicious in it and the recent sightings of the same service
+// and host.
+func (a *Analyzer) Analyze(it data.ListItem) data.ListItem {
+	t := it.LastSeen
+	if t.IsZero() {
+		t = time.Now()
+	}
+	it.Warnings = nil
+	a.prune(t)
+
+	// the same instance name answered by different hosts
+	if host := strings.ToLower(it.Host); host != "" {
+		a.hosts[it.ID()] = observe(a.hosts[it.ID()], host, t)
+		if hosts := values(a.hosts[it.ID()]); len(hosts) > 1 {
+			it.Warnings = append(it.Warnings, "name answered by several hosts: "+strings.Join(hosts, ", "))
+		}
+	}
+
+	// a host resolving to different addresses on the same link. Hosts
+	// often have several IPv6 addresses, so only IPv4 is compared.
+	if it.Host != "" && it.AddrV4 != "" {
+		k := strings.ToLower(it.Host) + " " + it.Interface
+		a.addrs[k] = observe(a.addrs[k], it.AddrV4, t)
+		if addrs := values(a.addrs[k]); len(addrs) > 1 {
+			w := fmt.Sprintf("host %s resolves to several addresses: %s", it.Host, strings.Join(addrs, ", "))
+			if it.Interface != "" {
+				w = fmt.Sprintf("host %s resolves to several addresses on %s: %s", it.Host, it.Interface, strings.Join(addrs, ", "))
+			}
+			it.Warnings = append(it.Warnings, w)
+		}
+	}
+
+	// rapid TXT record changes
+	state, seen := a.txt[it.ID()]
+	if seen && !slices.Equal(state.fields, it.InfoFields) {
+		state.changes = append(state.changes, t)
+	}
+	state.fields = it.InfoFields
+	state.seen = t
+	state.changes = slices.DeleteFunc(state.changes, func(c time.Time) bool { return t.Sub(c) > flapWindow })
+	a.txt[it.ID()] = state
+	if len(state.changes) >= flapChanges {
+		it.Warnings = append(it.Warnings, fmt.Sprintf("TXT record changed %d times in the last %d minutes", len(state.changes), int(flapWindow.Minutes())))
+	}
+
+	// multicast responses come from the local link, so an address outside
+	// the subnets of all interfaces was forwarded or forged. Services
+	// browsed via unicast DNS-SD are usually off-link. hashicorp/mdns does
+	// not expose the source of a response, so the advertised address is
+	// checked.
+	if isLocal(it.Domain) && offLink(it.AddrV4) {
+		it.Warnings = append(it.Warnings, "response from off-link address "+it.AddrV4)
+	}
+
+	return it
+}
+
+// prune forgets hosts and addresses that have not been seen within window
+// and TXT records not seen within flapWindow, at most once per window.
+func (a *Analyzer) prune(t time.Time) {
+	if t.Sub(a.pruned) < window {
+		return
+	}
+	a.pruned = t
+	for _, m := range []map[string][]sighting{a.hosts, a.addrs} {
+		for k, sightings := range m {
+			sightings = slices.DeleteFunc(sightings, func(s sighting) bool { return t.Sub(s.time) > window })
+			if len(sightings) == 0 {
+				delete(m, k)
+			} else {
+				m[k] = sightings
+			}
+		}
+	}
+	for k, state := range a.txt {
+		if t.Sub(state.seen) > flapWindow {
+			delete(a.txt, k)
+		}
+	}
+}
+
+// offLink reports whether addr is an IPv4 address outside the subnets of
+// all interfaces. IPv6 addresses are not checked, as hosts often advertise
+// prefixes that are not configured locally, and neither are IPv4 link-local
+// addresses, which are on-link by definition. If the interface addresses
+// cannot be read, no address is reported.
+func offLink(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() == nil || ip.IsLinkLocalUnicast() {
+		return false
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok && ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLocal reports whether domain is the multicast DNS domain
+func isLocal(domain string) bool {
+	return domain == "" || strings.EqualFold(strings.TrimSuffix(domain, "."), "local")
+}
@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sweep is how often discovery sees a service: a sweep of all service
+// types plus the default --interval
+const sweep = 9*time.Minute + 30*time.Second + time.Minute
+
+func flapping(it data.ListItem) bool {
+	return slices.ContainsFunc(it.Warnings, func(w string) bool { return strings.HasPrefix(w, "TXT record changed") })
+}
+
+func TestFlapping(t *testing.T) {
+	a := New()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	printer := data.ListItem{Name: "Printer._ipp._tcp.local.", Domain: "local"}
+	for i := range 4 {
+		// another service keeps the analyzer pruning in between
+		a.Analyze(data.ListItem{Name: "Other._http._tcp.local.", Domain: "local", LastSeen: start.Add(time.Duration(i)*sweep + sweep/2)})
+
+		printer.LastSeen = start.Add(time.Duration(i) * sweep)
+		printer.InfoFields = []string{fmt.Sprintf("status=%d", i)}
+		got := a.Analyze(printer)
+		if want := i == 3; flapping(got) != want {
+			t.Errorf("sweep %d: flapping = %v, want %v (warnings %q)", i, flapping(got), want, got.Warnings)
+		}
+	}
+}
+
+func TestNotFlapping(t *testing.T) {
+	a := New()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	printer := data.ListItem{Name: "Printer._ipp._tcp.local.", Domain: "local"}
+	for i := range 12 {
+		printer.LastSeen = start.Add(time.Duration(i) * sweep)
+		// changes every third sweep, i.e. about every half hour
+		printer.InfoFields = []string{fmt.Sprintf("status=%d", i/3)}
+		if got := a.Analyze(printer); flapping(got) {
+			t.Errorf("sweep %d: flagged as flapping: %q", i, got.Warnings)
+		}
+	}
+}
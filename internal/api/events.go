@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/data"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// keepAliveInterval is how often idle event streams send a keep-alive.
+const keepAliveInterval = 30 * time.Second
+
+// Event is the wire format of a change to the set of services.
+type Event struct {
+	Kind    string        `json:"kind"`
+	Service data.ListItem `json:"service"`
+}
+
+func newEvent(ev cache.Event) Event {
+	return Event{Kind: ev.Kind.String(), Service: ev.Item}
+}
+
+// events streams changes as Server-Sent Events, starting with an "added"
+// event for every service currently known.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	events, unsubscribe := s.Cache.SubscribeStream()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			b, err := json.Marshal(newEvent(ev))
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Kind, b); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// checkOrigin accepts WebSocket clients without an Origin header, e.g.
+// scripts, and pages served by the API's own host or an allowed origin, so
+// that other web sites cannot read the stream from a visitor's browser.
+func (s *Server) checkOrigin(_ *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("invalid origin %q: %w", origin, err)
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	for _, allowed := range s.AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q not allowed", origin)
+}
+
+// eventsWebSocket streams the same events as events, one JSON message per
+// event. Messages sent by the client are ignored.
+func (s *Server) eventsWebSocket() http.Handler {
+	return websocket.Server{
+		Handshake: s.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			events, unsubscribe := s.Cache.SubscribeStream()
+			defer unsubscribe()
+
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var msg string
+				for websocket.Message.Receive(ws, &msg) == nil {
+				}
+			}()
+
+			for {
+				select {
+				case <-closed:
+					return
+				case ev, ok := <-events:
+					if !ok {
+						return
+					}
+					if err := websocket.JSON.Send(ws, newEvent(ev)); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+}
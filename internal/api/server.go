@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/inventory"
+	"mdns-browser/internal/query"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Server exposes the discovery cache as a JSON HTTP API.
+type Server struct {
+	Cache   *cache.Cache
+	Metrics http.Handler // Served on /metrics when set
+
+	// AllowedOrigins are origins, e.g. https://dash.example, whose pages
+	// may open the WebSocket stream in addition to the API's own host.
+	AllowedOrigins []string
+}
+
+// Type summarizes the services of one service type.
+type Type struct {
+	Type     string   `json:"type"`
+	Count    int      `json:"count"`
+	Services []string `json:"services"`
+}
+
+// Host summarizes the services running on one host.
+type Host struct {
+	Host       string   `json:"host"`
+	Model      string   `json:"model,omitempty"`
+	OS         string   `json:"os,omitempty"`
+	Vendor     string   `json:"vendor,omitempty"`
+	AddrV4     []string `json:"addrV4,omitempty"`
+	AddrV6     []string `json:"addrV6,omitempty"`
+	Interfaces []string `json:"interfaces,omitempty"`
+	MACs       []string `json:"macs,omitempty"`
+	Services   []string `json:"services"`
+}
+
+// Handler returns the routes of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /services", s.services)
+	mux.HandleFunc("GET /services/{id...}", s.service)
+	mux.HandleFunc("GET /types", s.types)
+	mux.HandleFunc("GET /hosts", s.hosts)
+	mux.HandleFunc("GET /events", s.events)
+	mux.Handle("GET /events/ws", s.eventsWebSocket())
+	if s.Metrics != nil {
+		mux.Handle("GET /metrics", s.Metrics)
+	}
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// filterItems applies the query parameters to items. q is a query like the
+// TUI filter, type, host and domain match case-insensitively.
+func filterItems(items []data.ListItem, params url.Values) ([]data.ListItem, error) {
+	for _, param := range []struct {
+		name  string
+		field func(data.ListItem) string
+	}{
+		{"type", func(it data.ListItem) string { return it.Service }},
+		{"host", func(it data.ListItem) string { return it.Host }},
+		{"domain", func(it data.ListItem) string { return it.Domain }},
+	} {
+		want := params.Get(param.name)
+		if want == "" {
+			continue
+		}
+		items = slices.DeleteFunc(items, func(it data.ListItem) bool {
+			return !strings.EqualFold(strings.TrimSuffix(param.field(it), "."), strings.TrimSuffix(want, "."))
+		})
+	}
+
+	if q := params.Get("q"); q != "" {
+		parsed, err := query.Parse(q)
+		if err != nil {
+			return nil, err
+		}
+		items = parsed.Filter(items)
+	}
+
+	if items == nil {
+		items = []data.ListItem{}
+	}
+	return items, nil
+}
+
+func (s *Server) services(w http.ResponseWriter, r *http.Request) {
+	items, err := filterItems(s.Cache.Items(), r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) service(w http.ResponseWriter, r *http.Request) {
+	it, ok := s.Cache.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "service not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, it)
+}
+
+func (s *Server) types(w http.ResponseWriter, r *http.Request) {
+	items, err := filterItems(s.Cache.Items(), r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	types := []Type{}
+	for _, it := range items {
+		idx := slices.IndexFunc(types, func(t Type) bool { return strings.EqualFold(t.Type, it.Service) })
+		if idx == -1 {
+			types = append(types, Type{Type: it.Service, Services: []string{}})
+			idx = len(types) - 1
+		}
+		types[idx].Count++
+		types[idx].Services = append(types[idx].Services, it.ID())
+	}
+	slices.SortFunc(types, func(a, b Type) int { return strings.Compare(a.Type, b.Type) })
+	writeJSON(w, http.StatusOK, types)
+}
+
+func (s *Server) hosts(w http.ResponseWriter, r *http.Request) {
+	items, err := filterItems(s.Cache.Items(), r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hosts := []Host{}
+	for _, h := range inventory.Hosts(items) {
+		host := Host{
+			Host:       h.Name,
+			Model:      h.Model,
+			OS:         h.OS,
+			Vendor:     h.Vendor,
+			AddrV4:     h.AddrV4,
+			AddrV6:     h.AddrV6,
+			Interfaces: h.Interfaces,
+			MACs:       h.MACs,
+			Services:   []string{},
+		}
+		for _, it := range h.Services {
+			host.Services = append(host.Services, it.ID())
+		}
+		hosts = append(hosts, host)
+	}
+	writeJSON(w, http.StatusOK, hosts)
+}
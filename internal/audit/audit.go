@@ -0,0 +1,193 @@
+// Package audit reviews discovered services for potentially sensitive
+// exposure: remote access, unauthenticated file sharing, TXT records that
+// leak user names, serial numbers or software versions, and devices that
+// advertise on several networks.
+package audit
+
+import (
+	"cmp"
+	"fmt"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/inventory"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Severity ranks findings.
+type Severity string
+
+const (
+	High   Severity = "high"
+	Medium Severity = "medium"
+	Low    Severity = "low"
+)
+
+func (s Severity) rank() int {
+	return map[Severity]int{High: 0, Medium: 1, Low: 2}[s]
+}
+
+// Categories of findings.
+const (
+	RemoteAccess = "remote access"
+	FileSharing  = "file sharing"
+	TXTLeak      = "TXT leak"
+	MultiNetwork = "multiple networks"
+)
+
+// Finding is a single exposure.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Category string   `json:"category"`
+	Host     string   `json:"host"`
+	Service  string   `json:"service,omitempty"` // empty for findings about a host
+	Detail   string   `json:"detail"`
+}
+
+// Report is the result of an audit.
+type Report struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Services int           `json:"services"`
+	Hosts    int           `json:"hosts"`
+	Findings []Finding     `json:"findings"`
+}
+
+// Count returns the number of findings with severity s.
+func (r Report) Count(s Severity) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// remoteAccess are service types that give remote access to a device.
+var remoteAccess = map[string]struct {
+	severity Severity
+	name     string
+	note     string
+}{
+	"_telnet":   {High, "Telnet", "sends credentials in clear text"},
+	"_rfb":      {High, "VNC screen sharing", "often protected by a short password only"},
+	"_rdp":      {Medium, "Remote Desktop", ""},
+	"_ssh":      {Medium, "SSH remote login", ""},
+	"_sftp-ssh": {Medium, "SFTP over SSH", ""},
+}
+
+// fileSharing are service types that share files, with whether the
+// protocol authenticates users at all.
+var fileSharing = map[string]struct {
+	authenticates bool
+	name          string
+	note          string
+}{
+	"_nfs":        {false, "NFS", "trusts the user IDs sent by clients"},
+	"_ftp":        {true, "FTP", "sends credentials in clear text"},
+	"_webdav":     {true, "WebDAV", "without TLS"},
+	"_webdavs":    {true, "WebDAV", ""},
+	"_smb":        {true, "SMB file sharing", ""},
+	"_afpovertcp": {true, "AFP file sharing", ""},
+	"_adisk":      {true, "Time Machine backup disk", ""},
+}
+
+// detail describes a service by name and port with an optional note.
+func detail(name string, port int, note string) string {
+	s := fmt.Sprintf("%s on port %d", name, port)
+	if note != "" {
+		s += ", " + note
+	}
+	return s
+}
+
+// guestUsers are user names in TXT records that mean no authentication.
+var guestUsers = []string{"anonymous", "guest", "ftp"}
+
+// leakKeys are TXT keys whose values reveal something about a device or
+// its users, by the kind of information.
+var leakKeys = []struct {
+	kind     string
+	severity Severity
+	keys     []string
+}{
+	{"user name", Medium, []string{"u", "user", "username", "owner", "adminurl_user"}},
+	{"password", High, []string{"p", "pw", "pass", "password"}},
+	{"serial number", Medium, []string{"serial", "serialnumber", "sn", "usb_ser", "serialno"}},
+	{"hardware address", Low, []string{"deviceid", "mac", "macaddress"}},
+	{"software version", Low, []string{"version", "vers", "ver", "fv", "firmware", "fwvers", "osxvers", "srcvers", "swvers", "os_version"}},
+}
+
+// serviceType returns the first label of the service type, e.g. _ssh.
+func serviceType(it data.ListItem) string {
+	label, _, _ := strings.Cut(it.Service, ".")
+	return strings.ToLower(label)
+}
+
+// Audit reviews the services and returns the findings, most severe first.
+func Audit(items []data.ListItem) []Finding {
+	var findings []Finding
+	for _, it := range items {
+		typ := serviceType(it)
+		if r, ok := remoteAccess[typ]; ok {
+			findings = append(findings, Finding{r.severity, RemoteAccess, it.Host, it.Name, detail(r.name, it.Port, r.note)})
+		}
+		if fs, ok := fileSharing[typ]; ok {
+			findings = append(findings, fileSharingFinding(it, fs.authenticates, detail(fs.name, it.Port, fs.note)))
+		}
+		for _, f := range it.InfoFields {
+			k, v, ok := strings.Cut(f, "=")
+			if !ok || v == "" {
+				continue
+			}
+			for _, l := range leakKeys {
+				if slices.Contains(l.keys, strings.ToLower(k)) {
+					if l.kind == "user name" && slices.Contains(guestUsers, strings.ToLower(v)) {
+						// reported as file sharing
+						continue
+					}
+					if l.kind == "password" {
+						v = "****"
+					}
+					findings = append(findings, Finding{l.severity, TXTLeak, it.Host, it.Name, fmt.Sprintf("TXT %s=%s reveals a %s", k, v, l.kind)})
+				}
+			}
+		}
+	}
+
+	for _, h := range inventory.Hosts(items) {
+		if len(h.Interfaces) > 1 {
+			findings = append(findings, Finding{Medium, MultiNetwork, h.Name, "",
+				"advertises on " + strings.Join(h.Interfaces, ", ") + " and may bridge these networks"})
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return cmp.Or(
+			cmp.Compare(a.Severity.rank(), b.Severity.rank()),
+			cmp.Compare(a.Category, b.Category),
+			cmp.Compare(strings.ToLower(a.Host), strings.ToLower(b.Host)),
+			cmp.Compare(strings.ToLower(a.Service), strings.ToLower(b.Service)),
+		)
+	})
+	return findings
+}
+
+// fileSharingFinding rates a file sharing service. Services whose TXT
+// record names a guest user are treated as unauthenticated.
+func fileSharingFinding(it data.ListItem, authenticates bool, detail string) Finding {
+	f := Finding{Medium, FileSharing, it.Host, it.Name, detail}
+	if !authenticates {
+		f.Severity = High
+		return f
+	}
+	for _, field := range it.InfoFields {
+		k, v, _ := strings.Cut(field, "=")
+		if strings.EqualFold(k, "u") && slices.Contains(guestUsers, strings.ToLower(v)) {
+			f.Severity = High
+			f.Detail += ", allows " + v + " access"
+		}
+	}
+	return f
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Formats lists the names accepted by Write.
+var Formats = []string{"text", "json", "html"}
+
+// Write renders the report in the named format.
+func Write(w io.Writer, format string, r Report) error {
+	switch format {
+	case "text":
+		return Text(w, r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if r.Findings == nil {
+			r.Findings = []Finding{}
+		}
+		return enc.Encode(r)
+	case "html":
+		return htmlReport.Execute(w, r)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// Text writes the report as a plain text table.
+func Text(w io.Writer, r Report) error {
+	fmt.Fprintf(w, "mDNS audit of %s (%s)\n", r.Started.Local().Format(time.DateTime), r.Duration.Round(time.Second))
+	fmt.Fprintf(w, "%d services on %d hosts, %d findings: %d high, %d medium, %d low\n",
+		r.Services, r.Hosts, len(r.Findings), r.Count(High), r.Count(Medium), r.Count(Low))
+	if len(r.Findings) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tCATEGORY\tHOST\tSERVICE\tDETAIL")
+	for _, f := range r.Findings {
+		service := f.Service
+		if service == "" {
+			service = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Severity, f.Category, f.Host, service, f.Detail)
+	}
+	return tw.Flush()
+}
+
+var htmlReport = template.Must(template.New("audit").Funcs(template.FuncMap{
+	"datetime": func(t time.Time) string { return t.Local().Format(time.DateTime) },
+	"round":    func(d time.Duration) time.Duration { return d.Round(time.Second) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>mDNS audit {{datetime .Started}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+h1 { color: #7D56F4; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.4rem 0.8rem; border-bottom: 1px solid #ddd; vertical-align: top; }
+th { background: #f4f2fe; }
+.high { color: #c62828; font-weight: bold; }
+.medium { color: #ef6c00; font-weight: bold; }
+.low { color: #626262; }
+</style>
+</head>
+<body>
+<h1>mDNS audit</h1>
+<p>{{datetime .Started}}, browsed for {{round .Duration}}: {{.Services}} services on {{.Hosts}} hosts.</p>
+<p>{{len .Findings}} findings:
+<span class="high">{{.Count "high"}} high</span>,
+<span class="medium">{{.Count "medium"}} medium</span>,
+<span class="low">{{.Count "low"}} low</span>.</p>
+{{if .Findings}}
+<table>
+<tr><th>Severity</th><th>Category</th><th>Host</th><th>Service</th><th>Detail</th></tr>
+{{range .Findings}}<tr><td class="{{.Severity}}">{{.Severity}}</td><td>{{.Category}}</td><td>{{.Host}}</td><td>{{.Service}}</td><td>{{.Detail}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"context"
+	"mdns-browser/internal/data"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventKind describes how a service changed.
+type EventKind int
+
+const (
+	Added EventKind = iota
+	Updated
+	Removed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Updated:
+		return "updated"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Event is published to subscribers whenever the set of services changes.
+// Old holds the previous state of the item for Updated events.
+type Event struct {
+	Kind EventKind
+	Item data.ListItem
+	Old  data.ListItem
+}
+
+// subscriberBuffer is the number of events buffered per stream
+// subscriber. Stream subscribers that fall further behind are closed, so
+// that they do not silently miss events and can reconnect for a fresh
+// snapshot.
+const subscriberBuffer = 256
+
+// subscriber receives events on ch. Stream subscribers are sent events
+// directly and closed when they lag. Other subscribers never miss an
+// event: events are queued without limit and forwarded by a goroutine.
+type subscriber struct {
+	ch     chan Event
+	stream bool
+
+	mu    sync.Mutex
+	queue []Event
+	wake  chan struct{} // signals new events in queue
+	done  chan struct{} // closed on unsubscribe
+}
+
+// push queues ev for a subscriber that is not a stream
+func (s *subscriber) push(ev Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, ev)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// forward sends the queued events to ch until unsubscribed
+func (s *subscriber) forward() {
+	defer close(s.ch)
+	for {
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+		for {
+			s.mu.Lock()
+			if len(s.queue) == 0 {
+				s.queue = nil
+				s.mu.Unlock()
+				break
+			}
+			ev := s.queue[0]
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+			select {
+			case s.ch <- ev:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+type entry struct {
+	item     data.ListItem
+	lastSeen time.Time
+}
+
+// Cache holds the live set of discovered services keyed by ListItem.ID.
+type Cache struct {
+	mu    sync.RWMutex
+	items map[string]entry
+	subs  map[*subscriber]struct{}
+}
+
+func New() *Cache {
+	return &Cache{
+		items: make(map[string]entry),
+		subs:  make(map[*subscriber]struct{}),
+	}
+}
+
+// sameItem compares the discovered fields of two items, ignoring layout.
+func sameItem(a, b data.ListItem) bool {
+	return a.Name == b.Name && a.Host == b.Host && a.AddrV4 == b.AddrV4 &&
+		a.AddrV6 == b.AddrV6 && a.Port == b.Port && a.Info == b.Info &&
+		slices.Equal(a.InfoFields, b.InfoFields)
+}
+
+func (c *Cache) publish(ev Event) {
+	for s := range c.subs {
+		if !s.stream {
+			s.push(ev)
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			delete(c.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// Put records a sighting of a service and publishes Added or Updated
+// events when the service is new or its fields changed.
+func (c *Cache) Put(it data.ListItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := it.ID()
+	old, ok := c.items[id]
+	c.items[id] = entry{item: it, lastSeen: time.Now()}
+	switch {
+	case !ok:
+		c.publish(Event{Kind: Added, Item: it})
+	case !sameItem(old.item, it):
+		c.publish(Event{Kind: Updated, Item: it, Old: old.item})
+	}
+}
+
+// Expire removes services that have not been seen for maxAge.
+func (c *Cache) Expire(maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline := time.Now().Add(-maxAge)
+	for id, e := range c.items {
+		if e.lastSeen.Before(deadline) {
+			delete(c.items, id)
+			c.publish(Event{Kind: Removed, Item: e.item})
+		}
+	}
+}
+
+// Get returns the service with the given ID.
+func (c *Cache) Get(id string) (data.ListItem, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.items[strings.ToLower(id)]
+	return e.item, ok
+}
+
+// Items returns a snapshot of all services ordered by name.
+func (c *Cache) Items() []data.ListItem {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	items := make([]data.ListItem, 0, len(c.items))
+	for _, e := range c.items {
+		items = append(items, e.item)
+	}
+	slices.SortFunc(items, func(a, b data.ListItem) int {
+		return strings.Compare(a.ID(), b.ID())
+	})
+	return items
+}
+
+// Subscribe returns a channel of events, starting with an Added event for
+// every service currently in the cache, and a function to unsubscribe. No
+// event is dropped however far the subscriber falls behind, so it suits
+// in-process consumers.
+func (c *Cache) Subscribe() (<-chan Event, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := &subscriber{ch: make(chan Event), wake: make(chan struct{}, 1), done: make(chan struct{})}
+	for _, e := range c.items {
+		s.push(Event{Kind: Added, Item: e.item})
+	}
+	c.subs[s] = struct{}{}
+	go s.forward()
+
+	return s.ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subs[s]; ok {
+			delete(c.subs, s)
+			close(s.done)
+		}
+	}
+}
+
+// SubscribeStream is like Subscribe for clients on the network, whose
+// channel is closed when they fall more than subscriberBuffer events
+// behind.
+func (c *Cache) SubscribeStream() (<-chan Event, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := &subscriber{ch: make(chan Event, len(c.items)+subscriberBuffer), stream: true}
+	for _, e := range c.items {
+		s.ch <- Event{Kind: Added, Item: e.item}
+	}
+	c.subs[s] = struct{}{}
+
+	return s.ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subs[s]; ok {
+			delete(c.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// Run feeds the cache from in and expires stale services until in is
+// closed or ctx is cancelled. Services are checked for expiry every
+// quarter of maxAge, but at most once a second.
+func (c *Cache) Run(ctx context.Context, in <-chan data.ListItem, maxAge time.Duration) {
+	ticker := time.NewTicker(max(maxAge/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case it, ok := <-in:
+			if !ok {
+				return
+			}
+			c.Put(it)
+		case <-ticker.C:
+			c.Expire(maxAge)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"testing"
+	"time"
+)
+
+// TestSubscribeBurst checks that in-process subscribers receive every
+// event of a burst larger than the stream buffer, while stream
+// subscribers that do not read are closed.
+func TestSubscribeBurst(t *testing.T) {
+	c := New()
+	events, unsubscribe := c.Subscribe()
+	defer unsubscribe()
+	stream, unsubscribeStream := c.SubscribeStream()
+	defer unsubscribeStream()
+
+	const n = 3 * subscriberBuffer
+	for i := range n {
+		c.Put(data.ListItem{Name: fmt.Sprintf("s%d._http._tcp.local.", i)})
+	}
+	time.Sleep(10 * time.Millisecond)
+	c.Expire(0)
+
+	added, removed := 0, 0
+	for added+removed < 2*n {
+		select {
+		case ev := <-events:
+			if ev.Kind == Added {
+				added++
+			} else if ev.Kind == Removed {
+				removed++
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("got %d added and %d removed events, want %d each", added, removed, n)
+		}
+	}
+
+	for range stream {
+	}
+}
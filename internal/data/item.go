@@ -3,21 +3,105 @@ package data
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
 )
 
 type ListItem struct {
-	Name            string
-	Host            string
-	AddrV4          string
-	AddrV6          string
-	Port            int
-	Info            string
-	InfoFields      []string
-	MaxListWidth    int
-	MaxDetailsWidth int
+	Name            string        `json:"name"`
+	Instance        string        `json:"instance,omitempty"`
+	Service         string        `json:"service,omitempty"`
+	Domain          string        `json:"domain,omitempty"`
+	Host            string        `json:"host"`
+	AddrV4          string        `json:"addrV4,omitempty"`
+	AddrV6          string        `json:"addrV6,omitempty"`
+	Interface       string        `json:"interface,omitempty"`
+	MAC             string        `json:"mac,omitempty"`
+	Vendor          string        `json:"vendor,omitempty"`
+	Port            int           `json:"port"`
+	Info            string        `json:"info,omitempty"`
+	InfoFields      []string      `json:"infoFields,omitempty"`
+	LastSeen        time.Time     `json:"lastSeen,omitzero"`  // Time of the response
+	TTL             uint32        `json:"ttl,omitempty"`      // TTL of the SRV record in seconds, 0 if unknown
+	Latency         time.Duration `json:"latency,omitempty"`  // Time from the query to the response
+	Records         []Record      `json:"records,omitempty"`  // Resource records the service was resolved from
+	Warnings        []string      `json:"warnings,omitempty"` // Suspicious findings, e.g. name conflicts
+	Health          []CheckResult `json:"health,omitempty"`
+	Certificate     *CertInfo     `json:"certificate,omitempty"`
+	MaxListWidth    int           `json:"-"`
+	MaxDetailsWidth int           `json:"-"`
+}
+
+// Record is a DNS resource record in presentation format.
+type Record struct {
+	Name       string `json:"name"` // owner name, escaped
+	Type       string `json:"type"`
+	Class      string `json:"class"`
+	TTL        uint32 `json:"ttl"`
+	CacheFlush bool   `json:"cacheFlush,omitempty"` // mDNS cache-flush bit
+	Data       string `json:"data,omitempty"`
+}
+
+// CheckResult is the outcome of an active health check of a service.
+type CheckResult struct {
+	Check   string        `json:"check"`
+	OK      bool          `json:"ok"`
+	Detail  string        `json:"detail"`
+	Latency time.Duration `json:"latency"`
+	Time    time.Time     `json:"time"`
+}
+
+// CertInfo describes the TLS certificate presented by a service.
+type CertInfo struct {
+	Subject     string    `json:"subject,omitempty"`
+	SANs        []string  `json:"sans,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	KeyType     string    `json:"keyType,omitempty"`
+	SelfSigned  bool      `json:"selfSigned"`
+	MatchesHost bool      `json:"matchesHost"`
+	Warnings    []string  `json:"warnings,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// String summarizes the result for the details view.
+func (r CheckResult) String() string {
+	status := "✔"
+	if !r.OK {
+		status = "✘"
+	}
+	s := status + " " + r.Detail
+	if r.Latency > 0 {
+		latency := r.Latency.Round(time.Millisecond)
+		if latency == 0 {
+			latency = r.Latency.Round(time.Microsecond)
+		}
+		s += fmt.Sprintf(" in %s", latency)
+	}
+	return s + " at " + r.Time.Format(time.TimeOnly)
+}
+
+// ID identifies a service independently of letter case, it is the key
+// used to deduplicate items.
+func (i ListItem) ID() string {
+	return strings.ToLower(i.Name)
+}
+
+// RelativeHost returns the host name relative to the item's domain, e.g.
+// "printer" for "printer.local.". Hosts outside the domain are returned
+// fully qualified with a trailing dot.
+func (i ListItem) RelativeHost() string {
+	host := i.Host
+	if !strings.HasSuffix(host, ".") {
+		host += "."
+	}
+	if i.Domain != "" && len(host) > len(i.Domain) && strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(i.Domain)) {
+		return host[:len(host)-len(i.Domain)-1]
+	}
+	return host
 }
 
 func truncateString(title string, maxWidth int) string {
@@ -91,28 +175,37 @@ func wrapString(text string, maxWidth int) []string {
 	return lines
 }
 
+// Title is the name of the service, with a warning badge if it has
+// warnings.
 func (i ListItem) Title() string {
+	title := i.Name
 	if strings.TrimSpace(i.Name) == "" {
-		return truncateString(i.Host, i.MaxListWidth)
+		title = i.Host
+	}
+	if len(i.Warnings) > 0 {
+		title = "⚠ " + title
 	}
-	return truncateString(i.Name, i.MaxListWidth)
+	return truncateString(title, i.MaxListWidth)
 }
 func (i ListItem) Description() string {
 	return truncateString(i.Host, i.MaxListWidth)
 }
+
+// FilterValue returns the ID, which list filters use to look up the item
+// and evaluate structured queries against all of its fields.
 func (i ListItem) FilterValue() string {
-	return i.Details()
+	return i.ID()
 }
 
 // addWrappedValue adds a label-value pair with wrapping support for long values
-func (i ListItem) addWrappedValue(details []string, labelStyle lipgloss.Style, valueStyle lipgloss.Style, label, value string) []string {
+func addWrappedValue(details []string, labelStyle lipgloss.Style, valueStyle lipgloss.Style, label, value string, width int) []string {
 	if strings.TrimSpace(value) == "" {
 		return details
 	}
 
 	// Account for label width and padding when calculating available width for value
 	labelWidth := runewidth.StringWidth(label)
-	availableWidth := i.MaxDetailsWidth - labelWidth - 4 // Conservative padding estimate
+	availableWidth := width - labelWidth - 4 // Conservative padding estimate
 
 	wrappedLines := wrapString(value, availableWidth)
 
@@ -130,10 +223,120 @@ func (i ListItem) addWrappedValue(details []string, labelStyle lipgloss.Style, v
 	return details
 }
 
+// Field is a labelled value of the details view.
+type Field struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Section is a titled group of the details view. Fields are shown as
+// label-value pairs, Text as a wrapped paragraph and Items as a bullet list.
+type Section struct {
+	Title  string   `json:"title"`
+	Fields []Field  `json:"fields,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Items  []string `json:"items,omitempty"`
+}
+
+// Sections returns the content of the details view. The first section
+// holds the service details, further sections are only present when they
+// have content.
+func (i ListItem) Sections() []Section {
+	service := Section{Title: "🔍 Service Details"}
+	for _, f := range []Field{
+		{"Service Name", i.Name},
+		{"Service Type", i.Service},
+		{"Domain", i.Domain},
+		{"Host", i.Host},
+		{"IPv4 Address", i.AddrV4},
+		{"IPv6 Address", i.AddrV6},
+		{"Interface", i.Interface},
+		{"MAC Address", i.MAC},
+		{"Vendor", i.Vendor},
+	} {
+		if strings.TrimSpace(f.Value) != "" {
+			service.Fields = append(service.Fields, f)
+		}
+	}
+	if i.Port > 0 {
+		service.Fields = append(service.Fields, Field{"Port", fmt.Sprintf("%d", i.Port)})
+	}
+	sections := []Section{service}
+
+	// Warnings section, first as it may mean the details are not to be
+	// trusted
+	if len(i.Warnings) > 0 {
+		sections = append(sections, Section{Title: "⚠ Warnings", Items: i.Warnings})
+	}
+
+	// Additional information section
+	if strings.TrimSpace(i.Info) != "" {
+		sections = append(sections, Section{Title: "📋 Additional Information", Text: i.Info})
+	}
+
+	// Service fields section
+	var fields []string
+	for _, field := range i.InfoFields {
+		if strings.TrimSpace(field) != "" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) > 0 {
+		sections = append(sections, Section{Title: "🧰 Service Fields", Items: fields})
+	}
+
+	// Health check section
+	if len(i.Health) > 0 {
+		health := Section{Title: "🩺 Health"}
+		for _, r := range i.Health {
+			health.Fields = append(health.Fields, Field{r.Check, r.String()})
+		}
+		sections = append(sections, health)
+	}
+
+	// TLS certificate section
+	if c := i.Certificate; c != nil {
+		cert := Section{Title: "🔒 Certificate"}
+		if c.Error != "" {
+			cert.Fields = append(cert.Fields, Field{"Error", c.Error})
+		} else {
+			cert.Fields = append(cert.Fields,
+				Field{"Subject", c.Subject},
+				Field{"SANs", strings.Join(c.SANs, ", ")},
+				Field{"Issuer", c.Issuer},
+				Field{"Valid From", c.NotBefore.Format(time.DateTime)},
+				Field{"Expires", c.NotAfter.Format(time.DateTime)},
+				Field{"Key Type", c.KeyType},
+				Field{"Self-Signed", yesNo(c.SelfSigned)},
+				Field{"Matches Host", yesNo(c.MatchesHost)},
+			)
+			for _, w := range c.Warnings {
+				cert.Items = append(cert.Items, "⚠ "+w)
+			}
+		}
+		sections = append(sections, cert)
+	}
+
+	return sections
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
 // Details are used for the details view which is showing all the
 //
 //	properties of the item as a styled string using lipgloss
 func (i ListItem) Details() string {
+	return RenderSections(i.Sections(), i.MaxDetailsWidth)
+}
+
+// RenderSections renders sections in the style of the details view,
+// wrapping values to width.
+func RenderSections(sections []Section, width int) string {
 	// Define styles
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
@@ -159,43 +362,31 @@ func (i ListItem) Details() string {
 
 	var details []string
 
-	// Title
-	details = append(details, titleStyle.Render("🔍 Service Details"))
-
-	// Service details with wrapping
-	details = i.addWrappedValue(details, labelStyle, valueStyle, "Service Name: ", i.Name)
-	details = i.addWrappedValue(details, labelStyle, valueStyle, "Host: ", i.Host)
-	details = i.addWrappedValue(details, labelStyle, valueStyle, "IPv4 Address: ", i.AddrV4)
-	details = i.addWrappedValue(details, labelStyle, valueStyle, "IPv6 Address: ", i.AddrV6)
-
-	if i.Port > 0 {
-		details = i.addWrappedValue(details, labelStyle, valueStyle, "Port: ", fmt.Sprintf("%d", i.Port))
-	}
+	for n, section := range sections {
+		if n == 0 {
+			details = append(details, titleStyle.Render(section.Title))
+		} else {
+			details = append(details, "")
+			details = append(details, sectionStyle.Render(section.Title))
+		}
 
-	// Additional information section
-	if strings.TrimSpace(i.Info) != "" {
-		details = append(details, "")
-		details = append(details, sectionStyle.Render("📋 Additional Information"))
+		// Labelled values with wrapping
+		for _, f := range section.Fields {
+			details = addWrappedValue(details, labelStyle, valueStyle, f.Label+": ", f.Value, width)
+		}
 
-		// Wrap the info text
-		wrappedInfo := wrapString(i.Info, i.MaxDetailsWidth-4) // Account for padding
-		details = append(details, wrappedInfo...)
-	}
+		if section.Text != "" {
+			wrappedText := wrapString(section.Text, width-4) // Account for padding
+			details = append(details, wrappedText...)
+		}
 
-	// Service fields section
-	hasServiceFields := len(i.InfoFields) > 0 && len(i.InfoFields) != 1 || strings.TrimSpace(i.InfoFields[0]) != ""
-	if hasServiceFields {
-		details = append(details, "")
-		details = append(details, sectionStyle.Render("🧰 Service Fields"))
-		for _, field := range i.InfoFields {
-			if strings.TrimSpace(field) != "" {
-				// Wrap individual service fields
-				wrappedField := wrapString(field, i.MaxDetailsWidth-6) // Account for bullet and padding
-				if len(wrappedField) > 0 {
-					details = append(details, bulletStyle.Render("• ")+wrappedField[0])
-					for _, line := range wrappedField[1:] {
-						details = append(details, "  "+line) // Indent continuation lines
-					}
+		for _, item := range section.Items {
+			// Wrap individual items
+			wrappedItem := wrapString(item, width-6) // Account for bullet and padding
+			if len(wrappedItem) > 0 {
+				details = append(details, bulletStyle.Render("• ")+wrappedItem[0])
+				for _, line := range wrappedItem[1:] {
+					details = append(details, "  "+line) // Indent continuation lines
 				}
 			}
 		}
@@ -4,13 +4,23 @@ import (
 	"context"
 	"fmt"
 	"mdns-browser/internal/data"
+	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/mdns"
+	"github.com/miekg/dns"
 )
 
-func unescapeDNSName(s string) string {
+// cacheFlush is the top bit of the class of mDNS resource records, see RFC
+// 6762 section 10.2. In questions it requests a unicast response.
+const cacheFlush = 1 << 15
+
+// UnescapeDNSName decodes the backslash escapes of a presentation format
+// domain name, e.g. "My\ Printer" to "My Printer".
+func UnescapeDNSName(s string) string {
 	var b strings.Builder
 	for i := 0; i < len(s); i++ {
 		if s[i] == '\\' && i+1 < len(s) {
@@ -36,27 +46,135 @@ func unescapeDNSName(s string) string {
 	return b.String()
 }
 
+// EscapeDNSLabel escapes a single label for use in a presentation format
+// domain name. It is the inverse of UnescapeDNSName for one label: dots,
+// backslashes, the characters miekg/dns escapes and $, which starts a
+// directive in zone files, are escaped with a backslash, non-printable
+// bytes as \DDD. Other escapings of the same name are possible, so names
+// must be compared in wire format.
+func EscapeDNSLabel(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' || c == '\\' || c == '"' || c == '(' || c == ')' ||
+			c == ';' || c == '@' || c == '$' || c == ' ' || c == '\'':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// splitInstanceName splits an escaped service instance name such as
+// "My\ Printer._ipp._tcp.local." into its instance, service type and domain.
+func splitInstanceName(name string) (instance, service, domain string) {
+	labels := dns.SplitDomainName(name)
+	if len(labels) < 4 {
+		return UnescapeDNSName(name), "", ""
+	}
+	instance = UnescapeDNSName(labels[0])
+	service = labels[1] + "." + labels[2]
+	domain = dns.Fqdn(strings.Join(labels[3:], "."))
+	return instance, service, domain
+}
+
+// RecordOf converts a resource record to presentation format, with the
+// cache-flush bit split off the class.
+func RecordOf(rr dns.RR) data.Record {
+	h := rr.Header()
+	return data.Record{
+		Name:       h.Name,
+		Type:       dns.TypeToString[h.Rrtype],
+		Class:      dns.ClassToString[h.Class&^cacheFlush],
+		TTL:        h.Ttl,
+		CacheFlush: h.Class&cacheFlush != 0,
+		Data:       strings.TrimPrefix(rr.String(), h.String()),
+	}
+}
+
+// ipString formats an address, returning an empty string when it is unset.
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+func ipAddrString(addr *net.IPAddr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// ListAllServices queries all known service types via mDNS on the local
+// link and sends every resolved service to addCh. The caller owns addCh.
 func ListAllServices(ctx context.Context, addCh chan data.ListItem) error {
 	entriesCh := make(chan *mdns.ServiceEntry, 100)
+	// when each service type was queried, to measure the latency of its
+	// responses, including those arriving after the next query was sent
+	var queried struct {
+		sync.Mutex
+		start map[string]time.Time
+	}
+	queried.start = make(map[string]time.Time)
+
+	// timestamp entries as they arrive, before resolving interfaces and MAC
+	// addresses or waiting for addCh delays them
+	type received struct {
+		entry *mdns.ServiceEntry
+		at    time.Time
+	}
+	receivedCh := make(chan received, 100)
+	go func() {
+		defer close(receivedCh)
+		for entry := range entriesCh {
+			select {
+			case receivedCh <- received{entry, time.Now()}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	done := make(chan struct{})
 	go func() {
-		defer close(addCh)
+		defer close(done)
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case entry, ok := <-entriesCh:
+			case r, ok := <-receivedCh:
 				if !ok {
 					return
 				}
+				entry := r.entry
+				DefaultStats.ResponsesReceived.Add(1)
+				instance, service, domain := splitInstanceName(entry.Name)
 				it := data.ListItem{
-					Name:       unescapeDNSName(entry.Name),
+					Name:       UnescapeDNSName(entry.Name),
+					Instance:   instance,
+					Service:    service,
+					Domain:     domain,
 					Host:       entry.Host,
-					AddrV4:     entry.AddrV4.String(),
-					AddrV6:     entry.AddrV6IPAddr.String(),
+					AddrV4:     ipString(entry.AddrV4),
+					AddrV6:     ipAddrString(entry.AddrV6IPAddr),
 					Port:       entry.Port,
 					Info:       entry.Info,
 					InfoFields: entry.InfoFields,
+					LastSeen:   r.at,
+				}
+				it.Interface = interfaceFor(it.AddrV4, it.AddrV6)
+				it.MAC, it.Vendor = hardwareAddr(it.AddrV4, it.AddrV6)
+				queried.Lock()
+				if start, ok := queried.start[strings.ToLower(service)]; ok && r.at.After(start) {
+					it.Latency = r.at.Sub(start)
 				}
+				queried.Unlock()
 				select {
 				case <-ctx.Done():
 					return
@@ -65,7 +183,10 @@ func ListAllServices(ctx context.Context, addCh chan data.ListItem) error {
 			}
 		}
 	}()
+	// wait for the forwarding goroutine so that the caller may close addCh
+	defer func() { <-done }()
 
+	start := time.Now()
 	for _, svc := range Services {
 		select {
 		case <-ctx.Done():
@@ -76,7 +197,11 @@ func ListAllServices(ctx context.Context, addCh chan data.ListItem) error {
 
 		params := mdns.DefaultParams(svc)
 		params.Entries = entriesCh
-		params.Logger = NoopLogLogger
+		params.Logger = statsLogLogger
+		queried.Lock()
+		queried.start["_"+strings.ToLower(svc)+"._tcp"] = time.Now()
+		queried.Unlock()
+		DefaultStats.QueriesSent.Add(1)
 		err := mdns.QueryContext(ctx, params)
 		if err != nil {
 			close(entriesCh)
@@ -85,6 +210,23 @@ func ListAllServices(ctx context.Context, addCh chan data.ListItem) error {
 	}
 
 	close(entriesCh)
+	DefaultStats.Sweeps.Add(1)
+	DefaultStats.lastSweep.Store(int64(time.Since(start)))
 
 	return nil
 }
+
+// WatchServices repeatedly sweeps all known service types, pausing for
+// interval between sweeps, until ctx is cancelled. The caller owns addCh.
+func WatchServices(ctx context.Context, interval time.Duration, addCh chan data.ListItem) error {
+	for {
+		if err := ListAllServices(ctx, addCh); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
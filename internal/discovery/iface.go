@@ -0,0 +1,51 @@
+package discovery
+
+import (
+	"mdns-browser/internal/neighbor"
+	"net"
+	"strings"
+)
+
+// interfaceFor returns the name of the local interface a service was
+// reached on. Link-local IPv6 addresses carry it as their zone, otherwise
+// the interface whose subnet contains one of the addresses is used.
+func interfaceFor(addrV4, addrV6 string) string {
+	if _, zone, ok := strings.Cut(addrV6, "%"); ok {
+		return zone
+	}
+
+	ips := []net.IP{net.ParseIP(addrV4), net.ParseIP(addrV6)}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			for _, ip := range ips {
+				if ip != nil && ipNet.Contains(ip) {
+					return iface.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// hardwareAddr returns the MAC address and vendor of a service from the
+// neighbour table, preferring the IPv4 address.
+func hardwareAddr(addrV4, addrV6 string) (mac, vendor string) {
+	for _, addr := range []string{addrV4, addrV6} {
+		if hw := neighbor.Lookup(addr); hw != nil {
+			return hw.String(), neighbor.Vendor(hw)
+		}
+	}
+	return "", ""
+}
@@ -1,6 +1,7 @@
 package discovery
 
 import (
+	"bytes"
 	"context"
 	"log"
 	"log/slog"
@@ -32,3 +33,16 @@ func NewNoopLogLogger() *log.Logger {
 }
 
 var NoopLogLogger = NewNoopLogLogger()
+
+// malformedCounter drops all logs of the mdns client but counts the
+// packets it failed to unpack, which it only reports through its logger.
+type malformedCounter struct{}
+
+func (malformedCounter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte("Failed to unpack packet")) {
+		DefaultStats.MalformedPackets.Add(1)
+	}
+	return len(p), nil
+}
+
+var statsLogLogger = log.New(malformedCounter{}, "", 0)
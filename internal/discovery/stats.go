@@ -0,0 +1,23 @@
+package discovery
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts discovery activity since the program started.
+type Stats struct {
+	QueriesSent       atomic.Int64
+	ResponsesReceived atomic.Int64
+	MalformedPackets  atomic.Int64
+	Sweeps            atomic.Int64
+	lastSweep         atomic.Int64
+}
+
+// LastSweepDuration returns how long the last complete sweep took.
+func (s *Stats) LastSweepDuration() time.Duration {
+	return time.Duration(s.lastSweep.Load())
+}
+
+// DefaultStats is updated by all discovery functions.
+var DefaultStats Stats
@@ -0,0 +1,231 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mdns-browser/internal/data"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// UnicastOpts configures wide-area browsing via unicast DNS-SD (RFC 6763).
+type UnicastOpts struct {
+	Domains []string      // Domains to browse, e.g. "example.com."
+	Server  string        // DNS server as host:port
+	Timeout time.Duration // Timeout per DNS exchange, default 2 seconds
+}
+
+// DefaultDNSServer returns the first name server from /etc/resolv.conf.
+func DefaultDNSServer() (string, error) {
+	conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return "", err
+	}
+	if len(conf.Servers) == 0 {
+		return "", fmt.Errorf("no name servers configured")
+	}
+	return net.JoinHostPort(conf.Servers[0], conf.Port), nil
+}
+
+type unicastBrowser struct {
+	client *dns.Client
+	server string
+}
+
+// query performs a single exchange and returns the answer and additional
+// sections. NXDOMAIN and empty answers are not treated as errors.
+func (b *unicastBrowser) query(ctx context.Context, name string, qtype uint16) ([]dns.RR, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	DefaultStats.QueriesSent.Add(1)
+	resp, _, err := b.client.ExchangeContext(ctx, m, b.server)
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s %s: %w", name, dns.TypeToString[qtype], err)
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("error querying %s %s: %s", name, dns.TypeToString[qtype], dns.RcodeToString[resp.Rcode])
+	}
+	DefaultStats.ResponsesReceived.Add(1)
+	return append(resp.Answer, resp.Extra...), nil
+}
+
+// ptrRecords returns the PTR records of name.
+func (b *unicastBrowser) ptrRecords(ctx context.Context, name string) ([]*dns.PTR, error) {
+	rrs, err := b.query(ctx, name, dns.TypePTR)
+	if err != nil {
+		return nil, err
+	}
+	var ptrs []*dns.PTR
+	for _, rr := range rrs {
+		if ptr, ok := rr.(*dns.PTR); ok && strings.EqualFold(ptr.Hdr.Name, dns.Fqdn(name)) {
+			ptrs = append(ptrs, ptr)
+		}
+	}
+	return ptrs, nil
+}
+
+func (b *unicastBrowser) ptrs(ctx context.Context, name string) ([]string, error) {
+	ptrs, err := b.ptrRecords(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, ptr := range ptrs {
+		targets = append(targets, ptr.Ptr)
+	}
+	return targets, nil
+}
+
+// browseDomains returns the domain itself plus any browse domains it
+// advertises via b._dns-sd._udp and db._dns-sd._udp.
+func (b *unicastBrowser) browseDomains(ctx context.Context, domain string) []string {
+	domains := []string{dns.Fqdn(domain)}
+	for _, prefix := range []string{"b._dns-sd._udp.", "db._dns-sd._udp."} {
+		targets, err := b.ptrs(ctx, prefix+dns.Fqdn(domain))
+		if err != nil {
+			continue
+		}
+		for _, t := range targets {
+			if !slices.ContainsFunc(domains, func(d string) bool { return strings.EqualFold(d, t) }) {
+				domains = append(domains, t)
+			}
+		}
+	}
+	return domains
+}
+
+// serviceTypes enumerates the service types of a domain. Servers that do
+// not support service type enumeration are queried for the full catalogue.
+func (b *unicastBrowser) serviceTypes(ctx context.Context, domain string) []string {
+	targets, err := b.ptrs(ctx, "_services._dns-sd._udp."+domain)
+	if err == nil && len(targets) > 0 {
+		return targets
+	}
+	types := make([]string, 0, len(Services))
+	for _, svc := range Services {
+		types = append(types, "_"+svc+"._tcp."+domain)
+	}
+	return types
+}
+
+// resolve looks up SRV, TXT and address records of the service instance
+// ptr points to. The records used are kept in the item. It fails only if
+// the SRV record cannot be looked up; a missing TXT record is logged.
+func (b *unicastBrowser) resolve(ctx context.Context, ptr *dns.PTR) (data.ListItem, error) {
+	instance := ptr.Ptr
+	name, service, domain := splitInstanceName(instance)
+	it := data.ListItem{
+		Name:     UnescapeDNSName(instance),
+		Instance: name,
+		Service:  service,
+		Domain:   domain,
+		LastSeen: time.Now(),
+		Records:  []data.Record{RecordOf(ptr)},
+	}
+
+	start := time.Now()
+	rrs, err := b.query(ctx, instance, dns.TypeSRV)
+	if err != nil {
+		return it, err
+	}
+	it.Latency = time.Since(start)
+	for _, rr := range rrs {
+		if srv, ok := rr.(*dns.SRV); ok {
+			it.Host = srv.Target
+			it.Port = int(srv.Port)
+			it.TTL = srv.Hdr.Ttl
+			it.Records = append(it.Records, RecordOf(srv))
+			break
+		}
+	}
+
+	rrs, err = b.query(ctx, instance, dns.TypeTXT)
+	if err != nil {
+		slog.Warn("error resolving unicast DNS-SD service", "service", it.Name, "error", err)
+	}
+	for _, rr := range rrs {
+		if txt, ok := rr.(*dns.TXT); ok {
+			it.Info = strings.Join(txt.Txt, "|")
+			it.InfoFields = txt.Txt
+			it.Records = append(it.Records, RecordOf(txt))
+			break
+		}
+	}
+
+	if it.Host == "" {
+		return it, nil
+	}
+	if rrs, err = b.query(ctx, it.Host, dns.TypeA); err == nil {
+		for _, rr := range rrs {
+			if a, ok := rr.(*dns.A); ok {
+				it.AddrV4 = a.A.String()
+				it.Records = append(it.Records, RecordOf(a))
+				break
+			}
+		}
+	}
+	if rrs, err = b.query(ctx, it.Host, dns.TypeAAAA); err == nil {
+		for _, rr := range rrs {
+			if aaaa, ok := rr.(*dns.AAAA); ok {
+				it.AddrV6 = aaaa.AAAA.String()
+				it.Records = append(it.Records, RecordOf(aaaa))
+				break
+			}
+		}
+	}
+
+	return it, nil
+}
+
+// ListUnicastServices browses the configured domains via unicast DNS-SD and
+// sends every resolved service to addCh. Service types and instances that
+// fail to resolve are logged and skipped. The caller owns addCh.
+func ListUnicastServices(ctx context.Context, opts UnicastOpts, addCh chan data.ListItem) error {
+	if opts.Timeout == 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	b := &unicastBrowser{
+		client: &dns.Client{Timeout: opts.Timeout},
+		server: opts.Server,
+	}
+
+	for _, domain := range opts.Domains {
+		for _, d := range b.browseDomains(ctx, domain) {
+			for _, svc := range b.serviceTypes(ctx, d) {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				instances, err := b.ptrRecords(ctx, svc)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					slog.Warn("error browsing unicast DNS-SD", "type", svc, "error", err)
+					continue
+				}
+				for _, instance := range instances {
+					it, err := b.resolve(ctx, instance)
+					if err != nil {
+						if ctx.Err() != nil {
+							return ctx.Err()
+						}
+						slog.Warn("error resolving unicast DNS-SD service", "service", instance.Ptr, "error", err)
+						continue
+					}
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case addCh <- it:
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
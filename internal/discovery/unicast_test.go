@@ -0,0 +1,109 @@
+package discovery
+
+import (
+	"context"
+	"mdns-browser/internal/data"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// zone is served by the test DNS server. Names in failing answer SERVFAIL.
+var zone = []string{
+	"_services._dns-sd._udp.example.test. 60 IN PTR _http._tcp.example.test.",
+	"_services._dns-sd._udp.example.test. 60 IN PTR _ipp._tcp.example.test.",
+	"_http._tcp.example.test. 60 IN PTR Web\\ UI._http._tcp.example.test.",
+	"_http._tcp.example.test. 60 IN PTR Broken._http._tcp.example.test.",
+	"Web\\ UI._http._tcp.example.test. 120 IN SRV 0 0 8080 web.example.test.",
+	"Web\\ UI._http._tcp.example.test. 120 IN TXT \"path=/admin\"",
+	"web.example.test. 120 IN A 192.0.2.10",
+}
+
+var failing = []string{"_ipp._tcp.example.test.", "broken._http._tcp.example.test."}
+
+// serveZone starts a DNS server for zone on a local UDP port and returns
+// its address.
+func serveZone(t *testing.T) string {
+	t.Helper()
+	var rrs []dns.RR
+	for _, s := range zone {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch {
+		case slices.Contains(failing, strings.ToLower(q.Name)):
+			m.Rcode = dns.RcodeServerFailure
+		default:
+			for _, rr := range rrs {
+				if strings.EqualFold(rr.Header().Name, q.Name) && rr.Header().Rrtype == q.Qtype {
+					m.Answer = append(m.Answer, rr)
+				}
+			}
+			if len(m.Answer) == 0 {
+				m.Rcode = dns.RcodeNameError
+			}
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestListUnicastServices(t *testing.T) {
+	addr := serveZone(t)
+	addCh := make(chan data.ListItem, 10)
+	err := ListUnicastServices(context.Background(), UnicastOpts{Domains: []string{"example.test"}, Server: addr}, addCh)
+	if err != nil {
+		t.Fatalf("ListUnicastServices: %v", err)
+	}
+	close(addCh)
+
+	var items []data.ListItem
+	for it := range addCh {
+		items = append(items, it)
+	}
+	if len(items) != 1 {
+		t.Fatalf("got %d services, want 1: %+v", len(items), items)
+	}
+	it := items[0]
+	want := data.ListItem{
+		Name:       "Web UI._http._tcp.example.test.",
+		Instance:   "Web UI",
+		Service:    "_http._tcp",
+		Domain:     "example.test.",
+		Host:       "web.example.test.",
+		AddrV4:     "192.0.2.10",
+		Port:       8080,
+		InfoFields: []string{"path=/admin"},
+		TTL:        120,
+	}
+	if it.Name != want.Name || it.Instance != want.Instance || it.Service != want.Service || it.Domain != want.Domain ||
+		it.Host != want.Host || it.AddrV4 != want.AddrV4 || it.Port != want.Port || it.TTL != want.TTL ||
+		!slices.Equal(it.InfoFields, want.InfoFields) {
+		t.Errorf("got %+v, want %+v", it, want)
+	}
+
+	var types []string
+	for _, r := range it.Records {
+		types = append(types, r.Type)
+	}
+	if !slices.Equal(types, []string{"PTR", "SRV", "TXT", "A"}) {
+		t.Errorf("got records %v, want PTR, SRV, TXT and A", types)
+	}
+}
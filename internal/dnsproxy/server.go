@@ -0,0 +1,211 @@
+package dnsproxy
+
+import (
+	"context"
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/discovery"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ttl is used for all answers. Records come from a live cache that may
+// change at any time, so clients should not hold on to them for long.
+const ttl = 10
+
+// Server answers unicast DNS-SD queries for Domain from the discovery
+// cache, in the spirit of an RFC 8766 discovery proxy.
+type Server struct {
+	Addr   string // Listen address, e.g. ":5300"
+	Domain string // Domain the local services are published under
+	Cache  *cache.Cache
+}
+
+// ListenAndServe serves UDP and TCP until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	s.Domain = dns.Fqdn(strings.ToLower(s.Domain))
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc(s.Domain, s.handle)
+
+	errCh := make(chan error, 2)
+	servers := []*dns.Server{
+		{Addr: s.Addr, Net: "udp", Handler: mux},
+		{Addr: s.Addr, Net: "tcp", Handler: mux},
+	}
+	for _, srv := range servers {
+		go func() {
+			errCh <- srv.ListenAndServe()
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errCh:
+	}
+	for _, srv := range servers {
+		_ = srv.Shutdown()
+	}
+	return err
+}
+
+func (s *Server) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl}
+}
+
+// hostName moves a host name from the item's domain into the served domain,
+// e.g. "printer.local." becomes "printer.lab.example.".
+func (s *Server) hostName(it data.ListItem) string {
+	host := it.RelativeHost()
+	if strings.HasSuffix(host, ".") {
+		return host
+	}
+	return host + "." + s.Domain
+}
+
+func (s *Server) serviceName(it data.ListItem) string {
+	return it.Service + "." + s.Domain
+}
+
+func (s *Server) instanceName(it data.ListItem) string {
+	return discovery.EscapeDNSLabel(it.Instance) + "." + s.serviceName(it)
+}
+
+func (s *Server) srv(it data.ListItem) dns.RR {
+	return &dns.SRV{
+		Hdr:    s.header(s.instanceName(it), dns.TypeSRV),
+		Port:   uint16(it.Port),
+		Target: s.hostName(it),
+	}
+}
+
+func (s *Server) txt(it data.ListItem) dns.RR {
+	fields := it.InfoFields
+	if len(fields) == 0 {
+		// RFC 6763 section 6.1, a TXT record must contain at least one string
+		fields = []string{""}
+	}
+	return &dns.TXT{Hdr: s.header(s.instanceName(it), dns.TypeTXT), Txt: fields}
+}
+
+func (s *Server) addresses(it data.ListItem, qtype uint16) []dns.RR {
+	var rrs []dns.RR
+	name := s.hostName(it)
+	if ip := net.ParseIP(it.AddrV4); ip != nil && (qtype == dns.TypeA || qtype == dns.TypeANY) {
+		rrs = append(rrs, &dns.A{Hdr: s.header(name, dns.TypeA), A: ip})
+	}
+	// the zone of link-local addresses is meaningless to remote clients
+	addr6, _, _ := strings.Cut(it.AddrV6, "%")
+	if ip := net.ParseIP(addr6); ip != nil && (qtype == dns.TypeAAAA || qtype == dns.TypeANY) {
+		rrs = append(rrs, &dns.AAAA{Hdr: s.header(name, dns.TypeAAAA), AAAA: ip})
+	}
+	return rrs
+}
+
+// canonical returns a domain name in wire format with ASCII letters
+// lower-cased, so that names compare equal however their labels are
+// escaped.
+func canonical(name string) string {
+	buf := make([]byte, 256)
+	n, err := dns.PackDomainName(dns.Fqdn(name), buf, 0, nil, false)
+	if err != nil {
+		return strings.ToLower(name)
+	}
+	buf = buf[:n]
+	for i, c := range buf {
+		if 'A' <= c && c <= 'Z' {
+			buf[i] = c + 'a' - 'A'
+		}
+	}
+	return string(buf)
+}
+
+// answer builds the answer and additional sections for a question. found
+// reports whether the name exists at all.
+func (s *Server) answer(q dns.Question) (answer, extra []dns.RR, found bool) {
+	name := strings.ToLower(q.Name)
+	wire := canonical(q.Name)
+	wants := func(rrtype uint16) bool { return q.Qtype == rrtype || q.Qtype == dns.TypeANY }
+
+	switch name {
+	case s.Domain:
+		return nil, nil, true
+	case "b._dns-sd._udp." + s.Domain, "lb._dns-sd._udp." + s.Domain:
+		if wants(dns.TypePTR) {
+			answer = append(answer, &dns.PTR{Hdr: s.header(q.Name, dns.TypePTR), Ptr: s.Domain})
+		}
+		return answer, nil, true
+	}
+
+	seen := make(map[string]bool)
+	for _, it := range s.Cache.Items() {
+		if it.Service == "" || it.Instance == "" {
+			continue
+		}
+		switch {
+		case name == "_services._dns-sd._udp."+s.Domain:
+			found = true
+			svc := s.serviceName(it)
+			if wants(dns.TypePTR) && !seen[svc] {
+				seen[svc] = true
+				answer = append(answer, &dns.PTR{Hdr: s.header(q.Name, dns.TypePTR), Ptr: svc})
+			}
+		case name == strings.ToLower(s.serviceName(it)):
+			found = true
+			if wants(dns.TypePTR) {
+				answer = append(answer, &dns.PTR{Hdr: s.header(q.Name, dns.TypePTR), Ptr: s.instanceName(it)})
+				// RFC 6763 section 12.1, include SRV, TXT and addresses
+				extra = append(extra, s.srv(it), s.txt(it))
+				extra = append(extra, s.addresses(it, dns.TypeANY)...)
+			}
+		case wire == canonical(s.instanceName(it)):
+			found = true
+			if wants(dns.TypeSRV) {
+				answer = append(answer, s.srv(it))
+				extra = append(extra, s.addresses(it, dns.TypeANY)...)
+			}
+			if wants(dns.TypeTXT) {
+				answer = append(answer, s.txt(it))
+			}
+		case wire == canonical(s.hostName(it)):
+			found = true
+			for _, rr := range s.addresses(it, q.Qtype) {
+				if !seen[rr.String()] {
+					seen[rr.String()] = true
+					answer = append(answer, rr)
+				}
+			}
+		}
+	}
+	return answer, extra, found
+}
+
+func (s *Server) handle(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if len(r.Question) != 1 || r.Question[0].Qclass != dns.ClassINET {
+		m.SetRcode(r, dns.RcodeRefused)
+		_ = w.WriteMsg(m)
+		return
+	}
+
+	answer, extra, found := s.answer(r.Question[0])
+	if !found {
+		m.SetRcode(r, dns.RcodeNameError)
+	}
+	m.Answer = answer
+	m.Extra = extra
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		size := dns.MinMsgSize
+		if opt := r.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+		}
+		m.Truncate(size)
+	}
+	_ = w.WriteMsg(m)
+}
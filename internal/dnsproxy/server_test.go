@@ -0,0 +1,58 @@
+package dnsproxy
+
+import (
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/data"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// query returns the question for name as a server receives it, with the
+// name escaped by miekg/dns.
+func query(t *testing.T, name string, qtype uint16) dns.Question {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	b, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Unpack(b); err != nil {
+		t.Fatal(err)
+	}
+	return m.Question[0]
+}
+
+func TestAnswerEscapedInstance(t *testing.T) {
+	c := cache.New()
+	c.Put(data.ListItem{
+		Name:       "Bob's $5 Printer v1.2._ipp._tcp.local.",
+		Instance:   "Bob's $5 Printer v1.2",
+		Service:    "_ipp._tcp",
+		Domain:     "local.",
+		Host:       "printer.local.",
+		AddrV4:     "192.0.2.7",
+		Port:       631,
+		InfoFields: []string{"rp=ipp/print"},
+	})
+	s := &Server{Domain: "lab.example.", Cache: c}
+
+	ptrs, _, found := s.answer(query(t, "_ipp._tcp.lab.example.", dns.TypePTR))
+	if !found || len(ptrs) != 1 {
+		t.Fatalf("PTR query: found %v, %d answers, want 1", found, len(ptrs))
+	}
+	instance := ptrs[0].(*dns.PTR).Ptr
+
+	for _, qtype := range []uint16{dns.TypeSRV, dns.TypeTXT} {
+		answer, _, found := s.answer(query(t, instance, qtype))
+		if !found || len(answer) != 1 {
+			t.Errorf("%s query for %s: found %v, %d answers, want 1", dns.TypeToString[qtype], instance, found, len(answer))
+		}
+	}
+
+	addrs, _, found := s.answer(query(t, "PRINTER.lab.example.", dns.TypeA))
+	if !found || len(addrs) != 1 {
+		t.Errorf("A query: found %v, %d answers, want 1", found, len(addrs))
+	}
+}
@@ -0,0 +1,33 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/filesd"
+)
+
+// Formats lists the names accepted by Write.
+var Formats = []string{"json", "zone", "file_sd"}
+
+// Options holds settings used by individual formats.
+type Options struct {
+	Origin string        // Origin of zone files
+	FileSD filesd.Config // Mapping of file_sd targets
+}
+
+// Write renders the items in the named format.
+func Write(w io.Writer, format string, items []data.ListItem, opts Options) error {
+	switch format {
+	case "json":
+		return JSON(w, items)
+	case "zone":
+		return Zone(w, items, opts.Origin)
+	case "file_sd":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(filesd.TargetGroups(items, opts.FileSD))
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
@@ -0,0 +1,17 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"mdns-browser/internal/data"
+)
+
+// JSON writes the items as an indented JSON array.
+func JSON(w io.Writer, items []data.ListItem) error {
+	if items == nil {
+		items = []data.ListItem{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/discovery"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// zoneTTL is the default TTL written to exported zone files.
+const zoneTTL = 120
+
+// Zone writes the items as a DNS zone file with PTR, SRV, TXT, A and AAAA
+// records under origin, suitable for loading into BIND or similar servers.
+func Zone(w io.Writer, items []data.ListItem, origin string) error {
+	origin = dns.Fqdn(strings.ToLower(origin))
+	hdr := func(name string, rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: zoneTTL}
+	}
+	host := func(it data.ListItem) string {
+		h := it.RelativeHost()
+		if strings.HasSuffix(h, ".") {
+			return h
+		}
+		return h + "." + origin
+	}
+
+	var rrs []dns.RR
+	seen := make(map[string]bool)
+	add := func(rr dns.RR) {
+		if !seen[rr.String()] {
+			seen[rr.String()] = true
+			rrs = append(rrs, rr)
+		}
+	}
+
+	for _, it := range items {
+		if it.Instance == "" || it.Service == "" {
+			continue
+		}
+		service := it.Service + "." + origin
+		instance := discovery.EscapeDNSLabel(it.Instance) + "." + service
+		add(&dns.PTR{Hdr: hdr("_services._dns-sd._udp."+origin, dns.TypePTR), Ptr: service})
+		add(&dns.PTR{Hdr: hdr(service, dns.TypePTR), Ptr: instance})
+		if it.Host != "" {
+			add(&dns.SRV{Hdr: hdr(instance, dns.TypeSRV), Port: uint16(it.Port), Target: host(it)})
+		}
+		txt := it.InfoFields
+		if len(txt) == 0 {
+			txt = []string{""}
+		}
+		add(&dns.TXT{Hdr: hdr(instance, dns.TypeTXT), Txt: txt})
+
+		if it.Host == "" || strings.HasSuffix(it.RelativeHost(), ".") {
+			// only hosts inside the exported domain get address records
+			continue
+		}
+		if ip := net.ParseIP(it.AddrV4); ip != nil {
+			add(&dns.A{Hdr: hdr(host(it), dns.TypeA), A: ip})
+		}
+		addr6, _, _ := strings.Cut(it.AddrV6, "%")
+		if ip := net.ParseIP(addr6); ip != nil {
+			add(&dns.AAAA{Hdr: hdr(host(it), dns.TypeAAAA), AAAA: ip})
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "; services discovered by mdns-browser on %s\n$ORIGIN %s\n$TTL %d\n\n",
+		time.Now().Format(time.RFC3339), origin, zoneTTL); err != nil {
+		return err
+	}
+	for _, rr := range rrs {
+		if _, err := fmt.Fprintln(w, rr.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package filesd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/data"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// debounce delays rewriting the file so that bursts of events result in
+// a single write.
+const debounce = time.Second
+
+// TypeConfig selects a service type and describes how it is mapped.
+type TypeConfig struct {
+	Type   string            `json:"type"`             // Service type, e.g. "_http._tcp"
+	Target string            `json:"target,omitempty"` // "ipv4" (default), "ipv6" or "host"
+	Labels map[string]string `json:"labels,omitempty"` // Static labels added to the targets
+	TXT    []string          `json:"txt,omitempty"`    // TXT keys exported as labels, all when empty
+}
+
+// Config maps service types to scrape targets. All types are exported
+// with the default mapping when Types is empty.
+type Config struct {
+	Types []TypeConfig `json:"types"`
+}
+
+// LoadConfig reads a JSON mapping config.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// TargetGroup is one entry of a Prometheus file_sd file.
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// labelName turns a TXT key into a valid Prometheus label name. TXT labels
+// are target labels rather than __meta_ labels, so that they are kept
+// without relabeling.
+func labelName(key string) string {
+	return "mdns_txt_" + strings.ToLower(invalidLabelChars.ReplaceAllString(key, "_"))
+}
+
+func (cfg Config) typeConfig(service string) (TypeConfig, bool) {
+	if len(cfg.Types) == 0 {
+		return TypeConfig{}, true
+	}
+	for _, tc := range cfg.Types {
+		if strings.EqualFold(strings.TrimSuffix(tc.Type, "."), service) {
+			return tc, true
+		}
+	}
+	return TypeConfig{}, false
+}
+
+func target(it data.ListItem, mode string) string {
+	host := strings.TrimSuffix(it.Host, ".")
+	switch mode {
+	case "host":
+	case "ipv6":
+		if it.AddrV6 != "" {
+			host = it.AddrV6
+		}
+	default:
+		if it.AddrV4 != "" {
+			host = it.AddrV4
+		}
+	}
+	if host == "" || it.Port == 0 {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(it.Port))
+}
+
+// TargetGroups maps the items selected by cfg to target groups, one per
+// service. Discovered fields are exposed as __meta_mdns_* labels for
+// relabeling, the service type and TXT fields also as mdns_* target labels.
+// Of TXT keys that map to the same label name, the first one is used, as
+// RFC 6763 section 6.4 specifies for repeated keys.
+func TargetGroups(items []data.ListItem, cfg Config) []TargetGroup {
+	groups := []TargetGroup{}
+	for _, it := range items {
+		tc, ok := cfg.typeConfig(it.Service)
+		if !ok {
+			continue
+		}
+		t := target(it, tc.Target)
+		if t == "" {
+			continue
+		}
+
+		labels := map[string]string{
+			"__meta_mdns_name":         it.Name,
+			"__meta_mdns_instance":     it.Instance,
+			"__meta_mdns_service_type": it.Service,
+			"__meta_mdns_domain":       it.Domain,
+			"__meta_mdns_host":         strings.TrimSuffix(it.Host, "."),
+			"__meta_mdns_interface":    it.Interface,
+			"__meta_mdns_mac":          it.MAC,
+			"__meta_mdns_vendor":       it.Vendor,
+			"mdns_service_type":        it.Service,
+		}
+		for _, field := range it.InfoFields {
+			k, v, _ := strings.Cut(field, "=")
+			if k == "" {
+				continue
+			}
+			if len(tc.TXT) > 0 && !slices.ContainsFunc(tc.TXT, func(s string) bool { return strings.EqualFold(s, k) }) {
+				continue
+			}
+			if _, ok := labels[labelName(k)]; ok {
+				continue
+			}
+			labels[labelName(k)] = v
+		}
+		for k, v := range tc.Labels {
+			labels[k] = v
+		}
+
+		groups = append(groups, TargetGroup{Targets: []string{t}, Labels: labels})
+	}
+	return groups
+}
+
+// WriteFile atomically replaces path with the target groups of items.
+func WriteFile(path string, items []data.ListItem, cfg Config) error {
+	b, err := json.MarshalIndent(TargetGroups(items, cfg), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".file_sd-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp uses mode 0600, Prometheus may run as another user
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Run rewrites path whenever the set of services changes, until ctx is
+// cancelled. It fails if the subscription to the cache ends.
+func Run(ctx context.Context, c *cache.Cache, path string, cfg Config) error {
+	events, unsubscribe := c.Subscribe()
+	defer unsubscribe()
+
+	if err := WriteFile(path, c.Items(), cfg); err != nil {
+		return err
+	}
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-events:
+			if !ok {
+				return errors.New("cache subscription closed")
+			}
+			if pending == nil {
+				pending = time.After(debounce)
+			}
+		case <-pending:
+			pending = nil
+			if err := WriteFile(path, c.Items(), cfg); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mdns-browser/internal/data"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeout limits each individual check.
+const timeout = 5 * time.Second
+
+// Address returns the host:port used to reach a service, preferring the
+// IPv4 address, then the IPv6 address and finally the host name.
+func Address(it data.ListItem) string {
+	host := strings.TrimSuffix(it.Host, ".")
+	switch {
+	case it.AddrV4 != "":
+		host = it.AddrV4
+	case it.AddrV6 != "":
+		host = it.AddrV6
+	}
+	return net.JoinHostPort(host, strconv.Itoa(it.Port))
+}
+
+// txtValue returns the value of a TXT key.
+func txtValue(it data.ListItem, key string) string {
+	for _, field := range it.InfoFields {
+		k, v, _ := strings.Cut(field, "=")
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Check runs all checks that apply to the service: a TCP connect, an HTTP
+// request for _http and _https and an SSH banner grab for _ssh.
+func Check(ctx context.Context, it data.ListItem) []data.CheckResult {
+	if it.Port == 0 {
+		return []data.CheckResult{{Check: "TCP", Detail: "no port advertised", Time: time.Now()}}
+	}
+	results := []data.CheckResult{checkTCP(ctx, it)}
+	switch {
+	case strings.HasPrefix(it.Service, "_http._tcp"):
+		results = append(results, checkHTTP(ctx, it, "http"))
+	case strings.HasPrefix(it.Service, "_https._tcp"):
+		results = append(results, checkHTTP(ctx, it, "https"))
+	case strings.HasPrefix(it.Service, "_ssh._tcp"), strings.HasPrefix(it.Service, "_sftp-ssh._tcp"):
+		results = append(results, checkSSH(ctx, it))
+	}
+	return results
+}
+
+func dial(ctx context.Context, it data.ListItem) (net.Conn, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var d net.Dialer
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", Address(it))
+	return conn, time.Since(start), err
+}
+
+func checkTCP(ctx context.Context, it data.ListItem) data.CheckResult {
+	r := data.CheckResult{Check: "TCP", Time: time.Now()}
+	conn, latency, err := dial(ctx, it)
+	if err != nil {
+		r.Detail = err.Error()
+		return r
+	}
+	conn.Close()
+	r.OK = true
+	r.Latency = latency
+	r.Detail = "connected to " + Address(it)
+	return r
+}
+
+// httpClient is shared by all HTTP checks. Connections are not kept alive,
+// so every check connects afresh and no idle connections pile up.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		// devices on the local network mostly use self-signed certificates
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+func checkHTTP(ctx context.Context, it data.ListItem, scheme string) data.CheckResult {
+	r := data.CheckResult{Check: strings.ToUpper(scheme), Time: time.Now()}
+
+	path := txtValue(it, "path")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	// the zone of link-local addresses must be escaped in URLs
+	url := scheme + "://" + strings.Replace(Address(it), "%", "%25", 1) + path
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		r.Detail = err.Error()
+		return r
+	}
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		r.Detail = err.Error()
+		return r
+	}
+	defer resp.Body.Close()
+	r.Latency = time.Since(start)
+	r.OK = resp.StatusCode < 500
+	r.Detail = fmt.Sprintf("GET %s: %s", path, resp.Status)
+	if server := resp.Header.Get("Server"); server != "" {
+		r.Detail += " (" + server + ")"
+	}
+	return r
+}
+
+func checkSSH(ctx context.Context, it data.ListItem) data.CheckResult {
+	r := data.CheckResult{Check: "SSH", Time: time.Now()}
+	conn, _, err := dial(ctx, it)
+	if err != nil {
+		r.Detail = err.Error()
+		return r
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	_ = conn.SetReadDeadline(start.Add(timeout))
+	banner, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		r.Detail = "no banner: " + err.Error()
+		return r
+	}
+	r.Latency = time.Since(start)
+	banner = strings.TrimSpace(banner)
+	r.OK = strings.HasPrefix(banner, "SSH-")
+	r.Detail = banner
+	return r
+}
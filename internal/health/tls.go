@@ -0,0 +1,112 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"mdns-browser/internal/data"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// expiryWarning is how long before expiry a certificate is flagged.
+const expiryWarning = 30 * 24 * time.Hour
+
+// secureServices are the service types that speak TLS on their port.
+var secureServices = []string{
+	"_https", "_ipps", "_webdavs", "_ftps", "_imaps", "_pop3s",
+	"_ldaps", "_caldavs", "_carddavs", "_sips", "_smtps", "_xmpps",
+}
+
+// IsSecure reports whether a service type is expected to use TLS.
+func IsSecure(service string) bool {
+	name, _, _ := strings.Cut(service, ".")
+	return slices.Contains(secureServices, strings.ToLower(name))
+}
+
+// selfSigned reports whether cert is signed by its own key. Unlike
+// CheckSignatureFrom it does not require the certificate to be a CA, which
+// most self-signed device certificates are not.
+func selfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+func keyType(cert *x509.Certificate) string {
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
+
+// InspectCertificate performs a TLS handshake with the service and
+// describes the certificate it presents. Failures are reported in the
+// Error field.
+func InspectCertificate(ctx context.Context, it data.ListItem) *data.CertInfo {
+	host := strings.TrimSuffix(it.Host, ".")
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	d := tls.Dialer{
+		NetDialer: &net.Dialer{},
+		Config: &tls.Config{
+			ServerName: host,
+			// the certificate is inspected below instead of verified
+			InsecureSkipVerify: true,
+		},
+	}
+	conn, err := d.DialContext(ctx, "tcp", Address(it))
+	if err != nil {
+		return &data.CertInfo{Error: err.Error()}
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return &data.CertInfo{Error: "no certificate presented"}
+	}
+	cert := certs[0]
+
+	info := &data.CertInfo{
+		Subject:     cert.Subject.String(),
+		SANs:        cert.DNSNames,
+		Issuer:      cert.Issuer.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		KeyType:     keyType(cert),
+		SelfSigned:  selfSigned(cert),
+		MatchesHost: host != "" && cert.VerifyHostname(host) == nil,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	now := time.Now()
+	switch {
+	case now.After(cert.NotAfter):
+		info.Warnings = append(info.Warnings, "certificate expired on "+cert.NotAfter.Format(time.DateOnly))
+	case now.Before(cert.NotBefore):
+		info.Warnings = append(info.Warnings, "certificate not valid before "+cert.NotBefore.Format(time.DateOnly))
+	case cert.NotAfter.Sub(now) < expiryWarning:
+		info.Warnings = append(info.Warnings, "certificate expires on "+cert.NotAfter.Format(time.DateOnly))
+	}
+	if info.SelfSigned {
+		info.Warnings = append(info.Warnings, "certificate is self-signed")
+	}
+	if !info.MatchesHost {
+		info.Warnings = append(info.Warnings, "certificate does not match "+host)
+	}
+	return info
+}
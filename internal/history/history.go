@@ -0,0 +1,353 @@
+// Package history records sightings of services in a single JSON lines
+// file, so that questions like "when was this device last online?" can be
+// answered across runs.
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mdns-browser/internal/data"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// heartbeat is the time after which an unchanged service is recorded
+// again. It is well below DefaultGap, so that the sightings written still
+// show a service as present between its changes.
+const heartbeat = 10 * time.Minute
+
+// DefaultRetention is how long sightings are kept in the history file.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// DefaultGap is the gap between sightings after which a service is
+// considered to have been offline. Discovery sweeps of all service types
+// take several minutes, so it is much longer than a single sweep.
+const DefaultGap = 30 * time.Minute
+
+// Sighting is one record of a service being seen.
+type Sighting struct {
+	Time      time.Time `json:"time"`
+	Name      string    `json:"name"`
+	Host      string    `json:"host,omitempty"`
+	AddrV4    string    `json:"addrV4,omitempty"`
+	AddrV6    string    `json:"addrV6,omitempty"`
+	Interface string    `json:"interface,omitempty"`
+	TXTHash   string    `json:"txtHash,omitempty"`
+}
+
+// changed reports whether s differs from prev in anything but the time.
+func (s Sighting) changed(prev Sighting) bool {
+	prev.Time = s.Time
+	return s != prev
+}
+
+// Interval is a period in which a service was seen continuously.
+type Interval struct {
+	Start     time.Time
+	End       time.Time
+	Interface string
+}
+
+// Store appends sightings to a file and indexes the lines of each
+// service, so that the sightings of one service are read without scanning
+// the whole file.
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	f     *os.File
+	size  int64
+	index map[string][]span // lines by lower-cased name
+	thin  thinner
+}
+
+// span is the position of a line in the history file
+type span struct {
+	off int64
+	n   int
+}
+
+// thinner decides which sightings are written: those in which a service
+// changed, and otherwise one per heartbeat. The latest sighting that was
+// not written is held back and written before a gap or a change, so that
+// presence intervals keep their ends.
+type thinner struct {
+	last    map[string]Sighting // last written sighting by lower-cased name
+	pending map[string]Sighting // latest sighting not written yet
+}
+
+// add returns the sightings to write when s is seen.
+func (t *thinner) add(s Sighting) []Sighting {
+	id := strings.ToLower(s.Name)
+	var out []Sighting
+	if p, ok := t.pending[id]; ok && (s.Time.Sub(p.Time) > heartbeat || s.changed(p)) {
+		out = append(out, p)
+		t.last[id] = p
+	}
+	delete(t.pending, id)
+	if prev, ok := t.last[id]; ok && !s.changed(prev) && s.Time.Sub(prev.Time) < heartbeat {
+		t.pending[id] = s
+		return out
+	}
+	t.last[id] = s
+	return append(out, s)
+}
+
+// flush returns the sightings held back, oldest first.
+func (t *thinner) flush() []Sighting {
+	var out []Sighting
+	for id, p := range t.pending {
+		out = append(out, p)
+		t.last[id] = p
+	}
+	clear(t.pending)
+	slices.SortFunc(out, func(a, b Sighting) int { return a.Time.Compare(b.Time) })
+	return out
+}
+
+// DefaultPath returns the default location of the history file,
+// ~/.config/mdns-browser/history.jsonl on Linux.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "mdns-browser", "history.jsonl")
+}
+
+// Open opens the history file at path for appending, creating it if
+// necessary. The file is compacted first: sightings older than retention
+// are dropped, unless retention is 0, and unchanged sightings are thinned
+// out to one per heartbeat.
+func Open(path string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		path:  path,
+		index: make(map[string][]span),
+		thin:  thinner{last: make(map[string]Sighting), pending: make(map[string]Sighting)},
+	}
+	if err := s.compact(retention); err != nil {
+		return nil, fmt.Errorf("error compacting %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.f = f
+	return s, nil
+}
+
+// compact rewrites the history file with the sightings within retention
+// that the thinner keeps, and indexes them.
+func (s *Store) compact(retention time.Duration) error {
+	in, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".history-*.jsonl")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	cutoff := time.Now().Add(-retention)
+	w := bufio.NewWriter(tmp)
+	sc := bufio.NewScanner(in)
+	sc.Buffer(nil, 1024*1024)
+	for sc.Scan() {
+		var sighting Sighting
+		if err := json.Unmarshal(sc.Bytes(), &sighting); err != nil {
+			continue
+		}
+		if retention > 0 && sighting.Time.Before(cutoff) {
+			continue
+		}
+		for _, out := range s.thin.add(sighting) {
+			if err := s.write(w, out); err != nil {
+				return err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	for _, out := range s.thin.flush() {
+		if err := s.write(w, out); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// write appends a sighting to w, which writes to the end of the history
+// file, and indexes it.
+func (s *Store) write(w io.Writer, sighting Sighting) error {
+	b, err := json.Marshal(sighting)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	id := strings.ToLower(sighting.Name)
+	s.index[id] = append(s.index[id], span{off: s.size, n: len(b)})
+	s.size += int64(len(b))
+	return nil
+}
+
+// Close writes the sightings held back and closes the history file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, out := range s.thin.flush() {
+		errs = append(errs, s.write(s.f, out))
+	}
+	return errors.Join(append(errs, s.f.Close())...)
+}
+
+// TXTHash returns a short hash of TXT record fields, to tell when the TXT
+// record of a service changed without storing it.
+func TXTHash(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Record records a sighting of it seen at t. Sightings of an unchanged
+// service within a heartbeat of the previous record are held back, and
+// only the latest of them is written before a gap.
+func (s *Store) Record(it data.ListItem, t time.Time) error {
+	sighting := Sighting{
+		Time:      t.UTC(),
+		Name:      it.Name,
+		Host:      it.Host,
+		AddrV4:    it.AddrV4,
+		AddrV6:    it.AddrV6,
+		Interface: it.Interface,
+		TXTHash:   TXTHash(it.InfoFields),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, out := range s.thin.add(sighting) {
+		if err := s.write(s.f, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query returns the recorded sightings of services whose name matches the
+// glob pattern, ignoring case, in the order they were recorded.
+func Query(path, pattern string) ([]Sighting, error) {
+	pattern = strings.ToLower(pattern)
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return read(path, func(name string) bool {
+		ok, _ := filepath.Match(pattern, strings.ToLower(name))
+		return ok
+	})
+}
+
+// Sightings returns the recorded sightings of the service with the given
+// name, including the latest one if it has been held back.
+func (s *Store) Sightings(name string) ([]Sighting, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strings.ToLower(name)
+	var sightings []Sighting
+	for _, sp := range s.index[id] {
+		b := make([]byte, sp.n)
+		if _, err := s.f.ReadAt(b, sp.off); err != nil {
+			return nil, err
+		}
+		var sighting Sighting
+		if err := json.Unmarshal(b, &sighting); err != nil {
+			return nil, err
+		}
+		sightings = append(sightings, sighting)
+	}
+	if p, ok := s.thin.pending[id]; ok {
+		sightings = append(sightings, p)
+	}
+	return sightings, nil
+}
+
+// read returns the sightings in the file at path whose name matches.
+func read(path string, match func(name string) bool) ([]Sighting, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sightings []Sighting
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		var sighting Sighting
+		if err := json.Unmarshal(s.Bytes(), &sighting); err != nil {
+			// e.g. a line that is still being written
+			continue
+		}
+		if match(sighting.Name) {
+			sightings = append(sightings, sighting)
+		}
+	}
+	return sightings, s.Err()
+}
+
+// Presence merges the sightings of one service into the intervals in
+// which it was seen, starting a new interval after a gap longer than gap.
+func Presence(sightings []Sighting, gap time.Duration) []Interval {
+	sorted := slices.Clone(sightings)
+	slices.SortStableFunc(sorted, func(a, b Sighting) int { return a.Time.Compare(b.Time) })
+
+	var intervals []Interval
+	for _, s := range sorted {
+		if n := len(intervals); n > 0 && s.Time.Sub(intervals[n-1].End) <= gap {
+			intervals[n-1].End = s.Time
+			continue
+		}
+		intervals = append(intervals, Interval{Start: s.Time, End: s.Time, Interface: s.Interface})
+	}
+	return intervals
+}
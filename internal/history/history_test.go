@@ -0,0 +1,108 @@
+package history
+
+import (
+	"bytes"
+	"mdns-browser/internal/data"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecordThinsAndIndexes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := Open(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	printer := data.ListItem{Name: "Printer._ipp._tcp.local.", Host: "printer.local.", AddrV4: "192.168.1.20"}
+	other := data.ListItem{Name: "NAS._smb._tcp.local.", Host: "nas.local.", AddrV4: "192.168.1.30"}
+	start := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+
+	// seen every 10s for an hour, gone for an hour, then seen for a while
+	// with a changed address
+	var all []Sighting
+	for i := range 360 {
+		at := start.Add(time.Duration(i) * 10 * time.Second)
+		if err := store.Record(printer, at); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Record(other, at); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, Sighting{Time: at.UTC()})
+	}
+	printer.AddrV4 = "192.168.1.21"
+	for i := range 30 {
+		at := start.Add(2*time.Hour + time.Duration(i)*10*time.Second)
+		if err := store.Record(printer, at); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, Sighting{Time: at.UTC()})
+	}
+
+	sightings, err := store.Sightings("printer._IPP._tcp.local.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sightings) > 12 {
+		t.Errorf("%d sightings kept, want at most 12", len(sightings))
+	}
+	if got, want := Presence(sightings, DefaultGap), Presence(all, DefaultGap); !slices.Equal(got, want) {
+		t.Errorf("Presence() = %v, want %v", got, want)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Close writes the sightings held back
+	fromFile, err := Query(path, "printer*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Presence(fromFile, DefaultGap), Presence(all, DefaultGap); !slices.Equal(got, want) {
+		t.Errorf("Presence() from file = %v, want %v", got, want)
+	}
+	if n := slices.IndexFunc(fromFile, func(s Sighting) bool { return s.AddrV4 == "192.168.1.21" }); n == -1 {
+		t.Error("changed address not recorded")
+	}
+}
+
+func TestOpenCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	var b bytes.Buffer
+	old := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+	recent := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	for i := range 60 {
+		for _, at := range []time.Time{old, recent} {
+			b.WriteString(`{"time":"` + at.Add(time.Duration(i)*time.Minute).Format(time.RFC3339) + `","name":"Printer._ipp._tcp.local."}` + "\n")
+		}
+	}
+	b.WriteString("{not json\n")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	sightings, err := store.Sightings("Printer._ipp._tcp.local.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sightings) == 0 || len(sightings) > 8 {
+		t.Fatalf("%d sightings after compaction, want 1 to 8", len(sightings))
+	}
+	for _, s := range sightings {
+		if s.Time.Before(recent) {
+			t.Errorf("sighting at %s older than the retention kept", s.Time)
+		}
+	}
+	want := []Interval{{Start: recent, End: recent.Add(59 * time.Minute)}}
+	if got := Presence(sightings, DefaultGap); !slices.Equal(got, want) {
+		t.Errorf("Presence() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/data"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event kinds a hook can subscribe to.
+const (
+	EventAdded      = "added"
+	EventUpdated    = "updated"
+	EventRemoved    = "removed"
+	EventTXTChanged = "txt_changed"
+)
+
+// defaultTimeout limits how long a single action may run.
+const defaultTimeout = 30 * time.Second
+
+// Hook runs an action for the events of matching services. Either URL or
+// Command must be set.
+type Hook struct {
+	Events  []string `json:"events"`            // Event kinds, all when empty
+	Type    string   `json:"type,omitempty"`    // Service type, e.g. "_ssh._tcp"
+	Name    string   `json:"name,omitempty"`    // Glob matched against the instance name
+	TXT     string   `json:"txt,omitempty"`     // Required TXT key, or key=value
+	URL     string   `json:"url,omitempty"`     // URL to POST a JSON payload to
+	Command []string `json:"command,omitempty"` // Command to run with MDNS_* variables
+	Timeout string   `json:"timeout,omitempty"` // Action timeout, default 30s
+}
+
+// Config is the hooks configuration file.
+type Config struct {
+	Hooks []Hook `json:"hooks"`
+}
+
+// LoadConfig reads and validates a JSON hooks config.
+func LoadConfig(file string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing %s: %w", file, err)
+	}
+	for n, h := range cfg.Hooks {
+		if (h.URL == "") == (len(h.Command) == 0) {
+			return cfg, fmt.Errorf("hook %d: exactly one of url and command is required", n)
+		}
+		if _, err := path.Match(h.Name, ""); err != nil {
+			return cfg, fmt.Errorf("hook %d: invalid name pattern: %w", n, err)
+		}
+		if h.Timeout != "" {
+			if _, err := time.ParseDuration(h.Timeout); err != nil {
+				return cfg, fmt.Errorf("hook %d: invalid timeout: %w", n, err)
+			}
+		}
+		for _, ev := range h.Events {
+			if !slices.Contains([]string{EventAdded, EventUpdated, EventRemoved, EventTXTChanged}, ev) {
+				return cfg, fmt.Errorf("hook %d: unknown event %q", n, ev)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// Payload is the JSON body posted to URL hooks.
+type Payload struct {
+	Event    string         `json:"event"`
+	Time     time.Time      `json:"time"`
+	Service  data.ListItem  `json:"service"`
+	Previous *data.ListItem `json:"previous,omitempty"`
+}
+
+// kinds returns the hook event kinds of a cache event.
+func kinds(ev cache.Event) []string {
+	switch ev.Kind {
+	case cache.Added:
+		return []string{EventAdded}
+	case cache.Removed:
+		return []string{EventRemoved}
+	}
+	k := []string{EventUpdated}
+	if !slices.Equal(ev.Old.InfoFields, ev.Item.InfoFields) {
+		k = append(k, EventTXTChanged)
+	}
+	return k
+}
+
+func txtValue(it data.ListItem, key string) (string, bool) {
+	for _, field := range it.InfoFields {
+		k, v, _ := strings.Cut(field, "=")
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// matches reports whether the hook applies to the service.
+func (h Hook) matches(kind string, it data.ListItem) bool {
+	if len(h.Events) > 0 && !slices.Contains(h.Events, kind) {
+		return false
+	}
+	if h.Type != "" && !strings.EqualFold(strings.TrimSuffix(h.Type, "."), it.Service) {
+		return false
+	}
+	if h.Name != "" {
+		name := it.Instance
+		if name == "" {
+			name = it.Name
+		}
+		if ok, _ := path.Match(strings.ToLower(h.Name), strings.ToLower(name)); !ok {
+			return false
+		}
+	}
+	if h.TXT != "" {
+		key, want, hasValue := strings.Cut(h.TXT, "=")
+		v, ok := txtValue(it, key)
+		if !ok || hasValue && v != want {
+			return false
+		}
+	}
+	return true
+}
+
+var invalidEnvChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// Environ returns the MDNS_* variables describing a service event.
+func Environ(kind string, it data.ListItem) []string {
+	env := []string{
+		"MDNS_EVENT=" + kind,
+		"MDNS_NAME=" + it.Name,
+		"MDNS_INSTANCE=" + it.Instance,
+		"MDNS_TYPE=" + it.Service,
+		"MDNS_DOMAIN=" + it.Domain,
+		"MDNS_HOST=" + it.Host,
+		"MDNS_ADDR_V4=" + it.AddrV4,
+		"MDNS_ADDR_V6=" + it.AddrV6,
+		"MDNS_PORT=" + strconv.Itoa(it.Port),
+		"MDNS_INTERFACE=" + it.Interface,
+		"MDNS_TXT=" + it.Info,
+	}
+	for _, field := range it.InfoFields {
+		k, v, _ := strings.Cut(field, "=")
+		if k != "" {
+			env = append(env, "MDNS_TXT_"+invalidEnvChars.ReplaceAllString(strings.ToUpper(k), "_")+"="+v)
+		}
+	}
+	return env
+}
+
+func (h Hook) run(ctx context.Context, kind string, ev cache.Event) error {
+	timeout := defaultTimeout
+	if h.Timeout != "" {
+		timeout, _ = time.ParseDuration(h.Timeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if len(h.Command) > 0 {
+		cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+		cmd.Env = append(os.Environ(), Environ(kind, ev.Item)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+		}
+		return nil
+	}
+
+	payload := Payload{Event: kind, Time: time.Now(), Service: ev.Item}
+	if ev.Kind == cache.Updated {
+		payload.Previous = &ev.Old
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Run executes the configured hooks for every change of the cache until
+// ctx is cancelled. Actions run concurrently, failures are logged.
+func Run(ctx context.Context, c *cache.Cache, cfg Config) {
+	events, unsubscribe := c.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			for _, kind := range kinds(ev) {
+				for _, h := range cfg.Hooks {
+					if !h.matches(kind, ev.Item) {
+						continue
+					}
+					go func() {
+						if err := h.run(ctx, kind, ev); err != nil {
+							slog.Error("hook failed", "event", kind, "service", ev.Item.Name, "error", err)
+						}
+					}()
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package inventory aggregates discovered services by host and derives a
+// device profile from what the host advertises.
+package inventory
+
+import (
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/neighbor"
+	"slices"
+	"strings"
+)
+
+// Host is a device with all services that target it.
+type Host struct {
+	Name       string          `json:"host"`
+	Model      string          `json:"model,omitempty"`
+	OS         string          `json:"os,omitempty"`
+	Vendor     string          `json:"vendor,omitempty"`
+	AddrV4     []string        `json:"addrV4,omitempty"`
+	AddrV6     []string        `json:"addrV6,omitempty"`
+	Interfaces []string        `json:"interfaces,omitempty"`
+	MACs       []string        `json:"macs,omitempty"`
+	Services   []data.ListItem `json:"services"`
+}
+
+// modelKeys are TXT keys that carry a model name, in order of preference.
+// model is used by _device-info and AirPlay, md by Google Cast and HomeKit,
+// am by older AirPlay receivers, ty and product by printers.
+var modelKeys = []string{"model", "md", "am", "ty", "product", "usb_MDL"}
+
+// vendorKeys are TXT keys that carry a manufacturer.
+var vendorKeys = []string{"manufacturer", "usb_MFG", "mfg"}
+
+// applePrefixes are model identifiers of Apple devices.
+var applePrefixes = []string{"Mac", "iMac", "iPhone", "iPad", "iPod", "AppleTV", "AudioAccessory", "Watch"}
+
+// txt returns the value of a TXT key, ignoring the case of the key.
+func txt(it data.ListItem, key string) string {
+	for _, f := range it.InfoFields {
+		k, v, _ := strings.Cut(f, "=")
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// serviceType returns the first label of the service type, e.g. _ipp.
+func serviceType(it data.ListItem) string {
+	label, _, _ := strings.Cut(it.Service, ".")
+	return strings.ToLower(label)
+}
+
+// Hosts aggregates items by host name. Hosts and their services are
+// sorted by name.
+func Hosts(items []data.ListItem) []Host {
+	var hosts []Host
+	for _, it := range items {
+		name := strings.ToLower(it.Host)
+		if name == "" {
+			continue
+		}
+		idx := slices.IndexFunc(hosts, func(h Host) bool { return h.Name == name })
+		if idx == -1 {
+			hosts = append(hosts, Host{Name: name})
+			idx = len(hosts) - 1
+		}
+		h := &hosts[idx]
+		h.AddrV4 = appendUnique(h.AddrV4, it.AddrV4)
+		h.AddrV6 = appendUnique(h.AddrV6, it.AddrV6)
+		h.Interfaces = appendUnique(h.Interfaces, it.Interface)
+		h.MACs = appendUnique(h.MACs, it.MAC)
+		h.Services = append(h.Services, it)
+	}
+
+	for i := range hosts {
+		h := &hosts[i]
+		slices.SortFunc(h.Services, func(a, b data.ListItem) int { return strings.Compare(a.ID(), b.ID()) })
+		h.profile()
+	}
+	slices.SortFunc(hosts, func(a, b Host) int { return strings.Compare(a.Name, b.Name) })
+	return hosts
+}
+
+func appendUnique(list []string, s string) []string {
+	if s == "" || slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+// profile fills in model, OS and vendor from the services of the host.
+func (h *Host) profile() {
+	// _device-info exists only to describe the device, so it wins
+	for _, it := range h.Services {
+		if serviceType(it) == "_device-info" {
+			if v := txt(it, "model"); v != "" {
+				h.Model = v
+			}
+		}
+	}
+	for _, key := range modelKeys {
+		for _, it := range h.Services {
+			if h.Model == "" {
+				h.Model = txt(it, key)
+			}
+		}
+	}
+	for _, key := range vendorKeys {
+		for _, it := range h.Services {
+			if h.Vendor == "" {
+				h.Vendor = txt(it, key)
+			}
+		}
+	}
+
+	has := func(types ...string) bool {
+		return slices.ContainsFunc(h.Services, func(it data.ListItem) bool {
+			return slices.Contains(types, serviceType(it))
+		})
+	}
+	// the vendor from the OUI of the MAC address
+	for _, it := range h.Services {
+		if h.Vendor == "" && it.Vendor != neighbor.LocallyAdministered {
+			h.Vendor = it.Vendor
+		}
+	}
+	if h.Vendor == "" {
+		switch {
+		case slices.ContainsFunc(applePrefixes, func(p string) bool { return strings.HasPrefix(h.Model, p) }),
+			has("_companion-link", "_apple-mobdev2", "_homekit"):
+			h.Vendor = "Apple"
+		case has("_googlecast", "_googlezone"):
+			h.Vendor = "Google"
+		case has("_sonos"):
+			h.Vendor = "Sonos"
+		case has("_amzn-wplay", "_amzn-alexa"):
+			h.Vendor = "Amazon"
+		}
+	}
+
+	for _, it := range h.Services {
+		if serviceType(it) == "_device-info" {
+			if v := txt(it, "osxvers"); v != "" {
+				h.OS = "macOS (Darwin " + v + ")"
+			}
+		}
+	}
+	if h.OS == "" {
+		switch {
+		case strings.HasPrefix(h.Model, "iPhone") || strings.HasPrefix(h.Model, "iPad"):
+			h.OS = "iOS"
+		case strings.HasPrefix(h.Model, "AppleTV"):
+			h.OS = "tvOS"
+		case strings.HasPrefix(h.Model, "Mac") || strings.HasPrefix(h.Model, "iMac"):
+			h.OS = "macOS"
+		case has("_googlecast"):
+			h.OS = "Google Cast"
+		case has("_rdp"):
+			h.OS = "Windows"
+		case has("_workstation"):
+			// published by Avahi by default
+			h.OS = "Linux (Avahi)"
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mdns-browser/internal/cache"
+	"mdns-browser/internal/discovery"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// serviceKey identifies one series of the services gauge.
+type serviceKey struct {
+	Type      string
+	Interface string
+}
+
+// Collector exposes discovery activity and the discovery cache in the
+// Prometheus text exposition format.
+type Collector struct {
+	cache *cache.Cache
+
+	mu      sync.Mutex
+	added   int64
+	removed int64
+	// seen keeps every type and interface ever reported, so that the gauge
+	// drops to zero instead of vanishing when the last service disappears
+	seen map[serviceKey]bool
+}
+
+func New(c *cache.Cache) *Collector {
+	return &Collector{cache: c, seen: make(map[serviceKey]bool)}
+}
+
+// Run counts service churn until ctx is cancelled.
+func (m *Collector) Run(ctx context.Context) {
+	events, unsubscribe := m.cache.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			m.mu.Lock()
+			switch ev.Kind {
+			case cache.Added:
+				m.added++
+			case cache.Removed:
+				m.removed++
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// escapeLabel escapes a label value as required by the exposition format.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func writeMetric(w io.Writer, name, typ, help string, value any) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, typ, name, value)
+}
+
+// Write writes all metrics to w.
+func (m *Collector) Write(w io.Writer) {
+	counts := make(map[serviceKey]int)
+	for _, it := range m.cache.Items() {
+		counts[serviceKey{Type: it.Service, Interface: it.Interface}]++
+	}
+
+	m.mu.Lock()
+	for k := range counts {
+		m.seen[k] = true
+	}
+	keys := make([]serviceKey, 0, len(m.seen))
+	for k := range m.seen {
+		keys = append(keys, k)
+	}
+	added, removed := m.added, m.removed
+	m.mu.Unlock()
+
+	slices.SortFunc(keys, func(a, b serviceKey) int {
+		if c := strings.Compare(a.Type, b.Type); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Interface, b.Interface)
+	})
+	fmt.Fprint(w, "# HELP mdns_browser_services Services currently visible.\n# TYPE mdns_browser_services gauge\n")
+	for _, k := range keys {
+		fmt.Fprintf(w, "mdns_browser_services{type=\"%s\",interface=\"%s\"} %d\n",
+			escapeLabel(k.Type), escapeLabel(k.Interface), counts[k])
+	}
+
+	stats := &discovery.DefaultStats
+	writeMetric(w, "mdns_browser_queries_sent_total", "counter", "DNS queries sent.", stats.QueriesSent.Load())
+	writeMetric(w, "mdns_browser_responses_received_total", "counter", "Resolved responses received.", stats.ResponsesReceived.Load())
+	writeMetric(w, "mdns_browser_malformed_packets_total", "counter", "Received packets that could not be parsed.", stats.MalformedPackets.Load())
+	writeMetric(w, "mdns_browser_sweeps_total", "counter", "Completed discovery sweeps over all service types.", stats.Sweeps.Load())
+	writeMetric(w, "mdns_browser_sweep_duration_seconds", "gauge", "Duration of the last complete discovery sweep.", stats.LastSweepDuration().Seconds())
+	writeMetric(w, "mdns_browser_services_added_total", "counter", "Services that appeared.", added)
+	writeMetric(w, "mdns_browser_services_removed_total", "counter", "Services that disappeared.", removed)
+}
+
+// ServeHTTP serves the metrics for scraping.
+func (m *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.Write(w)
+}
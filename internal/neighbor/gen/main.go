@@ -0,0 +1,156 @@
+// Command gen builds a replacement for oui.txt of package neighbor from the
+// IEEE MA-L registry. Only the vendors listed below are kept, under short
+// names, so that the embedded database stays small. It is not run by go
+// generate, as the output should be reviewed before replacing the
+// hand-picked file.
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// registryURL is the CSV export of the IEEE MA-L (OUI) registry
+const registryURL = "https://standards-oui.ieee.org/oui/oui.csv"
+
+// vendors maps prefixes of IEEE organization names to the vendor names
+// shown. The first matching prefix wins, so more specific prefixes come
+// first.
+var vendors = []struct{ prefix, name string }{
+	{"Apple, Inc", "Apple"},
+	{"Espressif", "Espressif"},
+	{"Ubiquiti", "Ubiquiti"},
+	{"Sonos", "Sonos"},
+	{"Raspberry Pi Trading", "Raspberry Pi Trading"},
+	{"Raspberry Pi Foundation", "Raspberry Pi Foundation"},
+	{"Google", "Google"},
+	{"Amazon Technologies", "Amazon"},
+	{"VMware", "VMware"},
+	{"Hewlett Packard", "Hewlett Packard"},
+	{"TP-LINK", "TP-Link"},
+	{"Roku", "Roku"},
+	{"NETGEAR", "Netgear"},
+	{"Brother Industries", "Brother"},
+	{"AVM", "AVM"},
+	{"Xiaomi", "Xiaomi"},
+	{"Western Digital", "Western Digital"},
+	{"Seiko Epson", "Seiko Epson"},
+	{"QNAP", "QNAP"},
+	{"Philips Lighting", "Philips Lighting"},
+	{"Nest Labs", "Nest Labs"},
+	{"Dell", "Dell"},
+	{"Canon", "Canon"},
+	{"Synology", "Synology"},
+	{"Slim Devices", "Slim Devices"},
+	{"Samsung Electronics", "Samsung"},
+	{"Realtek", "Realtek"},
+	{"NVIDIA", "NVIDIA"},
+	{"Cisco Meraki", "Cisco Meraki"},
+	{"Cisco Systems", "Cisco"},
+}
+
+// virtual are the prefixes of virtual machines, which are registered to
+// companies other than the product's or not at all.
+var virtual = map[string]string{
+	"525400": "QEMU/KVM",
+	"080027": "Oracle VirtualBox",
+	"00155D": "Microsoft Hyper-V",
+}
+
+func main() {
+	in := flag.String("in", "", "read the registry from `file` instead of downloading it")
+	out := flag.String("o", "oui.gen.txt", "write the vendors to `file`")
+	flag.Parse()
+
+	if err := generate(*in, *out); err != nil {
+		slog.Error("error generating OUI database", "error", err)
+		os.Exit(1)
+	}
+}
+
+// generate reads the registry from in, or downloads it if in is empty, and
+// writes the OUIs of the known vendors to out.
+func generate(in, out string) error {
+	var r io.Reader
+	if in != "" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		resp, err := http.Get(registryURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("error downloading %s: %s", registryURL, resp.Status)
+		}
+		r = resp.Body
+	}
+
+	ouis, err := parse(r)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Code generated by internal/neighbor/gen from %s; DO NOT EDIT.\n", registryURL)
+	fmt.Fprintf(&b, "# OUI\tVendor\n")
+	for _, o := range ouis {
+		fmt.Fprintf(&b, "%s\t%s\n", o.prefix, o.name)
+	}
+	return os.WriteFile(out, []byte(b.String()), 0o644)
+}
+
+type oui struct{ prefix, name string }
+
+// parse returns the OUIs of the known vendors in the registry CSV, whose
+// columns are Registry, Assignment, Organization Name and Organization
+// Address, sorted by vendor and OUI.
+func parse(r io.Reader) ([]oui, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing registry: %w", err)
+	}
+
+	var ouis []oui
+	for _, rec := range records {
+		if len(rec) < 3 || rec[0] != "MA-L" {
+			continue
+		}
+		prefix := strings.ToUpper(rec[1])
+		if _, ok := virtual[prefix]; ok {
+			continue
+		}
+		org := strings.TrimSpace(rec[2])
+		for _, v := range vendors {
+			if len(org) >= len(v.prefix) && strings.EqualFold(org[:len(v.prefix)], v.prefix) {
+				ouis = append(ouis, oui{prefix, v.name})
+				break
+			}
+		}
+	}
+	for prefix, name := range virtual {
+		ouis = append(ouis, oui{prefix, name})
+	}
+	if len(ouis) == 0 {
+		return nil, fmt.Errorf("no known vendors in registry")
+	}
+	slices.SortFunc(ouis, func(a, b oui) int {
+		return cmp.Or(strings.Compare(a.name, b.name), strings.Compare(a.prefix, b.prefix))
+	})
+	return ouis, nil
+}
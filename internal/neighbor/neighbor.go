@@ -0,0 +1,99 @@
+// Package neighbor finds the MAC addresses of discovered services in the
+// kernel's neighbour table and resolves their vendors.
+//
+// mDNS responses do not carry MAC addresses, but the host has usually
+// resolved them already for the addresses it talked to. The table is only
+// read on Linux, from /proc/net/arp and via netlink; elsewhere no MAC
+// addresses are found.
+package neighbor
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAge is how long a read of the neighbour table is reused
+const maxAge = 5 * time.Second
+
+// LocallyAdministered is the vendor of locally administered addresses.
+const LocallyAdministered = "Locally administered"
+
+var (
+	mu      sync.Mutex
+	table   map[netip.Addr]net.HardwareAddr
+	readAt  time.Time
+	vendors = parseOUI(ouiData)
+)
+
+// ouiData maps OUIs to vendors, one "AABBCC<TAB>Vendor" per line. It is a
+// hand-picked subset of the IEEE registry covering vendors common on home
+// and office networks. ./gen builds a complete list of their OUIs from the
+// registry, which replaces the file once reviewed.
+//
+//go:embed oui.txt
+var ouiData []byte
+
+func parseOUI(b []byte) map[[3]byte]string {
+	vendors := make(map[[3]byte]string)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		prefix, vendor, ok := strings.Cut(s.Text(), "\t")
+		if !ok || strings.HasPrefix(prefix, "#") {
+			continue
+		}
+		oui, err := hex.DecodeString(prefix)
+		if err != nil || len(oui) != 3 {
+			continue
+		}
+		vendors[[3]byte(oui)] = vendor
+	}
+	return vendors
+}
+
+// valid reports whether mac is a unicast Ethernet address
+func valid(mac net.HardwareAddr) bool {
+	return len(mac) == 6 && mac[0]&0x01 == 0 && !bytes.Equal(mac, make([]byte, 6))
+}
+
+// Lookup returns the MAC address of addr, an IPv4 or IPv6 address with an
+// optional zone, or nil if the neighbour table has no entry for it.
+func Lookup(addr string) net.HardwareAddr {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if time.Since(readAt) > maxAge {
+		t, err := readTable()
+		if err != nil {
+			slog.Debug("error reading neighbour table", "error", err)
+		}
+		table, readAt = t, time.Now()
+	}
+	return table[ip.WithZone("").Unmap()]
+}
+
+// Vendor returns the vendor of mac from its OUI. Locally administered
+// addresses, such as the random addresses of phones, have no vendor.
+func Vendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 {
+		return ""
+	}
+	if v, ok := vendors[[3]byte(mac[:3])]; ok {
+		return v
+	}
+	if mac[0]&0x02 != 0 {
+		return LocallyAdministered
+	}
+	return ""
+}
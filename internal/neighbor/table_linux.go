@@ -0,0 +1,113 @@
+//go:build linux
+
+package neighbor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Neighbour states from linux/neighbour.h
+const (
+	nudIncomplete = 0x01
+	nudFailed     = 0x20
+	nudNoARP      = 0x40
+)
+
+// Neighbour attributes from linux/neighbour.h
+const (
+	ndaDst    = 1
+	ndaLLAddr = 2
+)
+
+// sizeofNdmsg is the size of struct ndmsg
+const sizeofNdmsg = 12
+
+// readTable reads the kernel's ARP cache from /proc/net/arp and the IPv4
+// and IPv6 neighbour tables via netlink. If netlink fails, e.g. in a
+// sandbox, the entries of /proc/net/arp are returned with the error.
+func readTable() (map[netip.Addr]net.HardwareAddr, error) {
+	table := make(map[netip.Addr]net.HardwareAddr)
+	if err := readProcARP(table); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := readNetlink(table); err != nil {
+		return table, fmt.Errorf("netlink: %w", err)
+	}
+	return table, nil
+}
+
+// readProcARP adds the complete entries of /proc/net/arp to table.
+func readProcARP(table map[netip.Addr]net.HardwareAddr) error {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Scan() // header
+	for s.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 || fields[2] == "0x0" {
+			continue
+		}
+		ip, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		if mac, err := net.ParseMAC(fields[3]); err == nil && valid(mac) {
+			table[ip] = mac
+		}
+	}
+	return s.Err()
+}
+
+// readNetlink adds the resolved entries of the neighbour tables to table.
+func readNetlink(table map[netip.Addr]net.HardwareAddr) error {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, syscall.AF_UNSPEC)
+	if err != nil {
+		return err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWNEIGH || len(m.Data) < sizeofNdmsg {
+			continue
+		}
+		state := binary.NativeEndian.Uint16(m.Data[8:10])
+		if state&(nudIncomplete|nudFailed|nudNoARP) != 0 {
+			continue
+		}
+
+		var ip netip.Addr
+		var mac net.HardwareAddr
+		// route attributes, each padded to a multiple of 4 bytes
+		for b := m.Data[sizeofNdmsg:]; len(b) >= syscall.SizeofRtAttr; {
+			l := int(binary.NativeEndian.Uint16(b[0:2]))
+			if l < syscall.SizeofRtAttr || l > len(b) {
+				break
+			}
+			switch binary.NativeEndian.Uint16(b[2:4]) {
+			case ndaDst:
+				ip, _ = netip.AddrFromSlice(b[syscall.SizeofRtAttr:l])
+			case ndaLLAddr:
+				mac = net.HardwareAddr(append([]byte(nil), b[syscall.SizeofRtAttr:l]...))
+			}
+			b = b[min((l+3)&^3, len(b)):]
+		}
+		if ip.IsValid() && valid(mac) {
+			table[ip.Unmap()] = mac
+		}
+	}
+	return nil
+}
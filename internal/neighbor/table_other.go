@@ -0,0 +1,13 @@
+//go:build !linux
+
+package neighbor
+
+import (
+	"net"
+	"net/netip"
+)
+
+// readTable returns no entries, the neighbour table is only read on Linux.
+func readTable() (map[netip.Addr]net.HardwareAddr, error) {
+	return nil, nil
+}
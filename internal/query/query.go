@@ -0,0 +1,312 @@
+// Package query implements a small query language for selecting services,
+// e.g.
+//
+//	type:_ipp host:*.local port:631 txt.ty~"HP" addr:192.168.1.0/24 !type:_device-info
+//
+// A query is a list of terms separated by white space, all of which must
+// match. A term is either a bare word, matched as a case-insensitive
+// substring of the name, type, host, addresses, interface and TXT record,
+// or a field followed by an operator and a value. Terms starting with !
+// are negated. Values containing white space can be quoted. A word with a
+// colon that does not start with a field, such as an IPv6 address or a
+// URL, is a bare word as well.
+//
+// The operator ":" compares the value with a glob pattern where * and ?
+// are wildcards, "~" matches a case-insensitive regular expression. Both
+// ignore case and trailing dots of DNS names. The fields are:
+//
+//	name      full service name or instance name
+//	type      service type, with or without the protocol, e.g. _ipp or _ipp._tcp
+//	domain    domain, e.g. local
+//	host      target host name
+//	addr      IPv4 or IPv6 address; ":" also accepts a CIDR prefix
+//	port      port; ":" also accepts a range such as 8000-8999
+//	iface     interface the service was seen on
+//	mac       MAC address
+//	vendor    vendor of the MAC address
+//	txt       any TXT field as key=value
+//	txt.KEY   value of the TXT field KEY
+package query
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed query. The zero Query matches every service.
+type Query struct {
+	src   string
+	terms []term
+}
+
+type term struct {
+	negate bool
+	match  func(data.ListItem) bool
+}
+
+// Parse parses a query.
+func Parse(s string) (Query, error) {
+	q := Query{src: strings.TrimSpace(s)}
+	p := parser{s: []rune(s)}
+	for {
+		p.skipSpace()
+		if p.done() {
+			return q, nil
+		}
+		t, err := p.term()
+		if err != nil {
+			return Query{}, err
+		}
+		q.terms = append(q.terms, t)
+	}
+}
+
+// String returns the query as it was parsed.
+func (q Query) String() string {
+	return q.src
+}
+
+// Match reports whether it matches all terms of the query.
+func (q Query) Match(it data.ListItem) bool {
+	for _, t := range q.terms {
+		if t.match(it) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the items matching the query.
+func (q Query) Filter(items []data.ListItem) []data.ListItem {
+	var matched []data.ListItem
+	for _, it := range items {
+		if q.Match(it) {
+			matched = append(matched, it)
+		}
+	}
+	return matched
+}
+
+type parser struct {
+	s   []rune
+	pos int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *parser) skipSpace() {
+	for !p.done() && unicode.IsSpace(p.s[p.pos]) {
+		p.pos++
+	}
+}
+
+// term parses a single, possibly negated, term.
+func (p *parser) term() (term, error) {
+	var t term
+	if p.s[p.pos] == '!' {
+		t.negate = true
+		p.pos++
+	}
+
+	start := p.pos
+	for !p.done() && (unicode.IsLetter(p.s[p.pos]) || unicode.IsDigit(p.s[p.pos]) || strings.ContainsRune("._-", p.s[p.pos])) {
+		p.pos++
+	}
+	field := strings.ToLower(string(p.s[start:p.pos]))
+	if !p.done() && p.pos > start && (p.s[p.pos] == '~' || p.s[p.pos] == ':' && isField(field)) {
+		op := p.s[p.pos]
+		p.pos++
+		value, err := p.value()
+		if err != nil {
+			return t, err
+		}
+		t.match, err = fieldMatcher(field, op, value)
+		return t, err
+	}
+
+	// a bare word
+	p.pos = start
+	value, err := p.value()
+	if err != nil {
+		return t, err
+	}
+	if value == "" {
+		return t, fmt.Errorf("empty term at position %d", start)
+	}
+	t.match = textMatcher(value)
+	return t, nil
+}
+
+// value parses a value up to the next white space outside of quotes.
+func (p *parser) value() (string, error) {
+	var b strings.Builder
+	quoted := false
+	for ; !p.done(); p.pos++ {
+		r := p.s[p.pos]
+		switch {
+		case r == '"':
+			quoted = !quoted
+			continue
+		case r == '\\' && quoted && p.pos+1 < len(p.s):
+			p.pos++
+			r = p.s[p.pos]
+		case unicode.IsSpace(r) && !quoted:
+			return b.String(), nil
+		}
+		b.WriteRune(r)
+	}
+	if quoted {
+		return "", fmt.Errorf("missing closing quote")
+	}
+	return b.String(), nil
+}
+
+// normalize lower-cases s and removes a trailing dot
+func normalize(s string) string {
+	return strings.TrimSuffix(strings.ToLower(s), ".")
+}
+
+// stringMatcher returns a function matching strings with op and value
+func stringMatcher(op rune, value string) (func(string) bool, error) {
+	if op == '~' {
+		re, err := regexp.Compile("(?i)" + value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value, err)
+		}
+		return func(s string) bool { return re.MatchString(strings.TrimSuffix(s, ".")) }, nil
+	}
+
+	// a glob pattern where * and ? are the only special characters
+	pattern := regexp.QuoteMeta(normalize(value))
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	re := regexp.MustCompile("^" + pattern + "$")
+	return func(s string) bool { return re.MatchString(normalize(s)) }, nil
+}
+
+// anyField returns a function matching items where any of the strings
+// returned by fields matches
+func anyField(match func(string) bool, fields func(data.ListItem) []string) func(data.ListItem) bool {
+	return func(it data.ListItem) bool {
+		for _, f := range fields(it) {
+			if match(f) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// fields are the names of the fields of a query, besides txt.KEY
+var fields = []string{"name", "type", "domain", "host", "addr", "port", "iface", "interface", "mac", "vendor", "txt"}
+
+// isField reports whether field is the name of a field
+func isField(field string) bool {
+	return slices.Contains(fields, field) || strings.HasPrefix(field, "txt.") && len(field) > len("txt.")
+}
+
+func fieldMatcher(field string, op rune, value string) (func(data.ListItem) bool, error) {
+	if field == "port" && op == ':' {
+		return portMatcher(value)
+	}
+	if field == "addr" && op == ':' {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			return prefixMatcher(prefix.Masked()), nil
+		}
+	}
+
+	match, err := stringMatcher(op, value)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case field == "name":
+		return anyField(match, func(it data.ListItem) []string { return []string{it.Name, it.Instance} }), nil
+	case field == "type":
+		return anyField(match, func(it data.ListItem) []string {
+			// allow _ipp and ipp for _ipp._tcp
+			label, _, _ := strings.Cut(it.Service, ".")
+			return []string{it.Service, label, strings.TrimPrefix(label, "_")}
+		}), nil
+	case field == "domain":
+		return anyField(match, func(it data.ListItem) []string { return []string{it.Domain} }), nil
+	case field == "host":
+		return anyField(match, func(it data.ListItem) []string { return []string{it.Host} }), nil
+	case field == "addr":
+		return anyField(match, func(it data.ListItem) []string { return []string{it.AddrV4, it.AddrV6} }), nil
+	case field == "port":
+		return anyField(match, func(it data.ListItem) []string { return []string{strconv.Itoa(it.Port)} }), nil
+	case field == "iface" || field == "interface":
+		return anyField(match, func(it data.ListItem) []string { return []string{it.Interface} }), nil
+	case field == "mac":
+		return anyField(match, func(it data.ListItem) []string { return []string{it.MAC} }), nil
+	case field == "vendor":
+		return anyField(match, func(it data.ListItem) []string { return []string{it.Vendor} }), nil
+	case field == "txt":
+		return anyField(match, func(it data.ListItem) []string { return it.InfoFields }), nil
+	case strings.HasPrefix(field, "txt.") && len(field) > len("txt."):
+		key := strings.TrimPrefix(field, "txt.")
+		return anyField(match, func(it data.ListItem) []string {
+			// RFC 6763 section 6.4, keys are case-insensitive
+			for _, f := range it.InfoFields {
+				k, v, _ := strings.Cut(f, "=")
+				if strings.EqualFold(k, key) {
+					return []string{v}
+				}
+			}
+			return nil
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown field %q", field)
+}
+
+// portMatcher matches a port or a range of ports such as 8000-8999
+func portMatcher(value string) (func(data.ListItem) bool, error) {
+	lo, hi, isRange := strings.Cut(value, "-")
+	from, err := strconv.Atoi(lo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", value)
+	}
+	to := from
+	if isRange {
+		if to, err = strconv.Atoi(hi); err != nil {
+			return nil, fmt.Errorf("invalid port range %q", value)
+		}
+	}
+	return func(it data.ListItem) bool { return it.Port >= from && it.Port <= to }, nil
+}
+
+// prefixMatcher matches services with an address in prefix
+func prefixMatcher(prefix netip.Prefix) func(data.ListItem) bool {
+	return func(it data.ListItem) bool {
+		for _, s := range []string{it.AddrV4, it.AddrV6} {
+			addr, err := netip.ParseAddr(s)
+			if err == nil && prefix.Contains(addr.WithZone("")) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// textMatcher matches value as a substring of the most important fields
+func textMatcher(value string) func(data.ListItem) bool {
+	value = strings.ToLower(value)
+	return func(it data.ListItem) bool {
+		fields := append([]string{it.Name, it.Service, it.Host, it.AddrV4, it.AddrV6, it.Interface}, it.InfoFields...)
+		for _, f := range fields {
+			if strings.Contains(strings.ToLower(f), value) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,97 @@
+package query
+
+import (
+	"mdns-browser/internal/data"
+	"testing"
+)
+
+var printer = data.ListItem{
+	Name:       "HP LaserJet._ipp._tcp.local.",
+	Instance:   "HP LaserJet",
+	Service:    "_ipp._tcp",
+	Domain:     "local",
+	Host:       "printer.local.",
+	AddrV4:     "192.168.1.20",
+	AddrV6:     "fe80::1",
+	Port:       631,
+	Interface:  "eth0",
+	InfoFields: []string{"ty=HP LaserJet Pro", "rp=ipp/print", "note="},
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"laserjet", true},
+		{"scanner", false},
+		{"type:_ipp", true},
+		{"type:ipp", true},
+		{"type:_ipp._tcp", true},
+		{"type:_http", false},
+		{"!type:_http", true},
+		{"!type:_ipp", false},
+		{"name:hp*", true},
+		{`name:"HP LaserJet"`, true},
+		{`"hp laserjet"`, true},
+		{"host:printer.local", true},
+		{"host:*.local.", true},
+		{"domain:local", true},
+		{"port:631", true},
+		{"port:600-700", true},
+		{"port:8000-8999", false},
+		{"addr:192.168.1.0/24", true},
+		{"addr:10.0.0.0/8", false},
+		{"addr:fe80::/10", true},
+		{"addr:192.168.1.*", true},
+		{"fe80::1", true},
+		{"fe80::2", false},
+		{"iface:eth0", true},
+		{"interface:wlan0", false},
+		{"txt:rp=ipp/print", true},
+		{"txt.ty~pro$", true},
+		{`txt.TY:"hp laserjet pro"`, true},
+		{"txt.rp:ipp/scan", false},
+		{"txt.missing:*", false},
+		{"txt.note:", true},
+		{"name~^hp type:_ipp port:631", true},
+		{"name~^hp type:_ipp port:80", false},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if got := q.Match(printer); got != tt.want {
+			t.Errorf("Parse(%q).Match() = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMatchURL(t *testing.T) {
+	it := data.ListItem{Name: "Router._http._tcp.local.", InfoFields: []string{"url=http://192.168.1.1/admin"}}
+	q, err := Parse("http://192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Match(it) {
+		t.Errorf("Parse(%q).Match() = false, want true", q)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		`name:"HP`,
+		"name~[",
+		"port:http",
+		"port:80-x",
+		"color~red",
+		"!",
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", query)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package traffic
+
+import (
+	"cmp"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/discovery"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// typeOrder is the order in which the records of a service are listed,
+// following how a service is resolved. Other types come last.
+var typeOrder = []string{"PTR", "SRV", "TXT", "A", "AAAA"}
+
+// pruneInterval is how often expired records are removed from a RecordSet
+const pruneInterval = time.Minute
+
+// RecordSet keeps the most recent version of every resource record seen in
+// responses, so that the records a service was resolved from can be shown
+// although hashicorp/mdns does not expose them. Records are indexed by the
+// names they belong to and expire with their TTL.
+type RecordSet struct {
+	records map[string]entry
+	byName  map[string]map[string]bool // record keys by lower-cased unescaped name
+	pruned  time.Time
+}
+
+// entry is a record with the time it expires
+type entry struct {
+	data.Record
+	expires time.Time
+}
+
+// NewRecordSet returns an empty RecordSet.
+func NewRecordSet() *RecordSet {
+	return &RecordSet{records: make(map[string]entry), byName: make(map[string]map[string]bool)}
+}
+
+func recordKey(r data.Record) string {
+	return strings.ToLower(r.Name) + " " + r.Type + " " + r.Data
+}
+
+// names returns the names a record is indexed by: its owner, and the
+// service a PTR record points to.
+func names(r data.Record) []string {
+	names := []string{strings.ToLower(discovery.UnescapeDNSName(r.Name))}
+	if r.Type == "PTR" {
+		names = append(names, strings.ToLower(discovery.UnescapeDNSName(strings.TrimSpace(r.Data))))
+	}
+	return names
+}
+
+func (s *RecordSet) add(k string, e entry) {
+	s.records[k] = e
+	for _, n := range names(e.Record) {
+		if s.byName[n] == nil {
+			s.byName[n] = make(map[string]bool)
+		}
+		s.byName[n][k] = true
+	}
+}
+
+func (s *RecordSet) remove(k string) {
+	e, ok := s.records[k]
+	if !ok {
+		return
+	}
+	delete(s.records, k)
+	for _, n := range names(e.Record) {
+		delete(s.byName[n], k)
+		if len(s.byName[n]) == 0 {
+			delete(s.byName, n)
+		}
+	}
+}
+
+// Add adds the records of a response. Records with the cache-flush bit set
+// replace the records of the same name and type from earlier packets, and
+// goodbye records with a TTL of 0 remove them.
+func (s *RecordSet) Add(p Packet) {
+	if !p.Response {
+		return
+	}
+	added := make(map[string]bool)
+	for _, r := range p.Records {
+		if r.Section == "question" {
+			continue
+		}
+		k := recordKey(r.Record)
+		if r.CacheFlush {
+			for old := range s.byName[names(r.Record)[0]] {
+				if e := s.records[old]; !added[old] && e.Type == r.Type && strings.EqualFold(e.Name, r.Name) {
+					s.remove(old)
+				}
+			}
+		}
+		s.remove(k)
+		if r.TTL == 0 {
+			continue
+		}
+		s.add(k, entry{Record: r.Record, expires: p.Time.Add(time.Duration(r.TTL) * time.Second)})
+		added[k] = true
+	}
+	s.prune(p.Time)
+}
+
+// prune removes expired records, at most once per pruneInterval.
+func (s *RecordSet) prune(now time.Time) {
+	if now.Sub(s.pruned) < pruneInterval {
+		return
+	}
+	s.pruned = now
+	for k, e := range s.records {
+		if now.After(e.expires) {
+			s.remove(k)
+		}
+	}
+}
+
+// For returns the records of a service: the PTR records pointing to it, the
+// records it owns such as SRV and TXT, and the address records of its host.
+// Records already in it.Records are not repeated, expired records are left
+// out.
+func (s *RecordSet) For(it data.ListItem) []data.Record {
+	records := slices.Clone(it.Records)
+	seen := make(map[string]bool)
+	for _, r := range records {
+		seen[recordKey(r)] = true
+	}
+	now := time.Now()
+	match := func(name string, types ...string) {
+		for k := range s.byName[name] {
+			e := s.records[k]
+			if seen[k] || now.After(e.expires) || len(types) > 0 && !slices.Contains(types, e.Type) {
+				continue
+			}
+			seen[k] = true
+			records = append(records, e.Record)
+		}
+	}
+	match(strings.ToLower(it.Name))
+	if it.Host != "" {
+		match(strings.ToLower(dns.Fqdn(it.Host)), "A", "AAAA")
+	}
+	slices.SortStableFunc(records, func(a, b data.Record) int {
+		return cmp.Or(
+			cmp.Compare(typeRank(a.Type), typeRank(b.Type)),
+			cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)),
+			cmp.Compare(a.Data, b.Data),
+		)
+	})
+	return records
+}
+
+// Lookup returns the record of type typ owned by name, e.g. the SRV record
+// of a service, and when it expires. Expired records are returned until
+// they are pruned.
+func (s *RecordSet) Lookup(name, typ string) (data.Record, time.Time, bool) {
+	for k := range s.byName[strings.ToLower(name)] {
+		if e := s.records[k]; e.Type == typ && strings.EqualFold(names(e.Record)[0], name) {
+			return e.Record, e.expires, true
+		}
+	}
+	return data.Record{}, time.Time{}, false
+}
+
+func typeRank(t string) int {
+	if i := slices.Index(typeOrder, t); i != -1 {
+		return i
+	}
+	return len(typeOrder)
+}
@@ -0,0 +1,192 @@
+// Package traffic passively listens to mDNS traffic on the local link and
+// decodes every packet, for debugging why a device does not show up.
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/discovery"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// unicastResponse is the top bit of the class of mDNS questions, which
+// asks for a unicast response.
+const unicastResponse = 1 << 15
+
+var (
+	groupV4 = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+	groupV6 = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: 5353}
+)
+
+// Record is a decoded question or resource record. For questions the
+// cache-flush bit is the unicast-response bit.
+type Record struct {
+	Section string // question, answer, authority or additional
+	data.Record
+}
+
+// Packet is a decoded mDNS packet.
+type Packet struct {
+	Time      time.Time
+	Src       string
+	Interface string
+	Local     bool // sent by this host
+	Response  bool
+	Records   []Record
+	Err       string // why the packet could not be decoded
+}
+
+// Decode decodes an mDNS message.
+func Decode(b []byte) (response bool, records []Record, err error) {
+	var m dns.Msg
+	if err := m.Unpack(b); err != nil {
+		return false, nil, err
+	}
+	for _, q := range m.Question {
+		records = append(records, Record{Section: "question", Record: data.Record{
+			Name:       q.Name,
+			Type:       dns.TypeToString[q.Qtype],
+			Class:      dns.ClassToString[q.Qclass&^unicastResponse],
+			CacheFlush: q.Qclass&unicastResponse != 0,
+		}})
+	}
+	for _, section := range []struct {
+		name string
+		rrs  []dns.RR
+	}{{"answer", m.Answer}, {"authority", m.Ns}, {"additional", m.Extra}} {
+		for _, rr := range section.rrs {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			records = append(records, Record{Section: section.name, Record: discovery.RecordOf(rr)})
+		}
+	}
+	return m.Response, records, nil
+}
+
+func interfaceName(index int) string {
+	if iface, err := net.InterfaceByIndex(index); err == nil {
+		return iface.Name
+	}
+	return ""
+}
+
+// localAddrs returns the addresses of all interfaces of this host.
+func localAddrs() map[string]bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	local := make(map[string]bool)
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			local[ipNet.IP.String()] = true
+		}
+	}
+	return local
+}
+
+// multicastInterfaces returns the interfaces that are up and support
+// multicast.
+func multicastInterfaces() []net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var multicast []net.Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagMulticast != 0 {
+			multicast = append(multicast, iface)
+		}
+	}
+	return multicast
+}
+
+// reader reads a packet into b and returns the index of the interface it
+// arrived on
+type reader func(b []byte) (n int, src net.Addr, ifIndex int, err error)
+
+// Listen joins the mDNS multicast groups on all interfaces and sends every
+// packet received to ch until ctx is cancelled. It returns an error if
+// neither IPv4 nor IPv6 could be joined. The caller owns ch.
+func Listen(ctx context.Context, ch chan<- Packet) error {
+	var readers []reader
+	var conns []net.PacketConn
+
+	if c, err := net.ListenMulticastUDP("udp4", nil, groupV4); err == nil {
+		p := ipv4.NewPacketConn(c)
+		for _, iface := range multicastInterfaces() {
+			_ = p.JoinGroup(&iface, groupV4)
+		}
+		_ = p.SetControlMessage(ipv4.FlagInterface, true)
+		conns = append(conns, c)
+		readers = append(readers, func(b []byte) (int, net.Addr, int, error) {
+			n, cm, src, err := p.ReadFrom(b)
+			if cm == nil {
+				return n, src, 0, err
+			}
+			return n, src, cm.IfIndex, err
+		})
+	}
+	if c, err := net.ListenMulticastUDP("udp6", nil, groupV6); err == nil {
+		p := ipv6.NewPacketConn(c)
+		for _, iface := range multicastInterfaces() {
+			_ = p.JoinGroup(&iface, groupV6)
+		}
+		_ = p.SetControlMessage(ipv6.FlagInterface, true)
+		conns = append(conns, c)
+		readers = append(readers, func(b []byte) (int, net.Addr, int, error) {
+			n, cm, src, err := p.ReadFrom(b)
+			if cm == nil {
+				return n, src, 0, err
+			}
+			return n, src, cm.IfIndex, err
+		})
+	}
+	if len(conns) == 0 {
+		return fmt.Errorf("error listening on port 5353 for IPv4 and IPv6")
+	}
+
+	go func() {
+		<-ctx.Done()
+		for _, c := range conns {
+			_ = c.Close()
+		}
+	}()
+
+	local := localAddrs()
+	var wg sync.WaitGroup
+	for _, read := range readers {
+		wg.Go(func() {
+			buf := make([]byte, 9000)
+			for {
+				n, src, index, err := read(buf)
+				if err != nil {
+					// closed when ctx is cancelled
+					return
+				}
+				p := Packet{Time: time.Now(), Src: src.String(), Interface: interfaceName(index)}
+				if udp, ok := src.(*net.UDPAddr); ok {
+					p.Local = local[udp.IP.String()]
+				}
+				if p.Response, p.Records, err = Decode(buf[:n]); err != nil {
+					p.Err = err.Error()
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- p:
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"mdns-browser/internal/actions"
+	"mdns-browser/internal/data"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// message reporting the end of a launched action
+type actionDoneMsg struct {
+	name string
+	err  error
+}
+
+// actionMenu lists the actions of the selected service
+type actionMenu struct {
+	item    data.ListItem
+	actions []actions.Action
+	cursor  int
+}
+
+// openMenu opens the action menu for the selected item.
+func (m model) openMenu() (model, tea.Cmd) {
+	it, ok := m.list.SelectedItem().(data.ListItem)
+	if !ok {
+		return m, nil
+	}
+	acts := m.actions.For(it)
+	if len(acts) == 0 {
+		return m, m.list.NewStatusMessage("no actions for " + it.Service)
+	}
+	m.menu = &actionMenu{item: it, actions: acts}
+	return m, nil
+}
+
+// updateMenu handles keys while the action menu is open.
+func (m model) updateMenu(msg tea.KeyMsg) (model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q":
+		m.menu = nil
+	case "k", "up":
+		if m.menu.cursor > 0 {
+			m.menu.cursor--
+		}
+	case "j", "down":
+		if m.menu.cursor < len(m.menu.actions)-1 {
+			m.menu.cursor++
+		}
+	case "enter", "o":
+		menu := m.menu
+		m.menu = nil
+		return m, runAction(menu.actions[menu.cursor], menu.item)
+	}
+	return m, nil
+}
+
+// runAction launches an action. Commands take over the terminal until
+// they exit, URLs are handed to the system and return immediately.
+func runAction(a actions.Action, it data.ListItem) tea.Cmd {
+	cmd, err := a.Cmd(it)
+	if err != nil {
+		return func() tea.Msg { return actionDoneMsg{name: a.Name, err: err} }
+	}
+	if a.URL != "" {
+		return func() tea.Msg {
+			return actionDoneMsg{name: a.Name, err: cmd.Run()}
+		}
+	}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return actionDoneMsg{name: a.Name, err: err}
+	})
+}
+
+// actionTarget shows what an action runs for a service: the expanded URL,
+// or the arguments passed to the command, quoted where needed.
+func actionTarget(a actions.Action, it data.ListItem) string {
+	if a.URL != "" {
+		url, err := a.ExpandURL(it)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return url
+	}
+	cmd, err := a.Cmd(it)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	args := make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		args[i] = arg
+		if arg == "" || strings.ContainsFunc(arg, func(r rune) bool { return unicode.IsSpace(r) || strings.ContainsRune(`"'\$`, r) }) {
+			args[i] = strconv.Quote(arg)
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// View renders the action menu in place of the details.
+func (menu *actionMenu) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#7D56F4")).
+		MarginBottom(1)
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#04B575")).
+		Bold(true)
+	hintStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#888888")).
+		MarginTop(1)
+
+	lines := []string{titleStyle.Render("🚀 Actions for " + menu.item.Title())}
+	for i, a := range menu.actions {
+		target := actionTarget(a, menu.item)
+		line := "  " + a.Name + " (" + target + ")"
+		if i == menu.cursor {
+			line = selectedStyle.Render("> " + a.Name + " (" + target + ")")
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, hintStyle.Render("enter: run • esc: close"))
+	return strings.Join(lines, "\n")
+}
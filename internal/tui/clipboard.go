@@ -0,0 +1,102 @@
+package tui
+
+import (
+	"encoding/json"
+	"mdns-browser/internal/actions"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/health"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyToClipboard sets the clipboard via OSC52, which works over SSH in
+// supporting terminals, and additionally via the system clipboard when
+// running locally. It returns an error only if no method was available.
+func copyToClipboard(text string) error {
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case os.Getenv("STY") != "":
+		seq = seq.Screen()
+	}
+	_, oscErr := seq.WriteTo(os.Stderr)
+
+	if os.Getenv("SSH_TTY") != "" {
+		return oscErr
+	}
+	if err := clipboard.WriteAll(text); err != nil && oscErr != nil {
+		return err
+	}
+	return nil
+}
+
+// serviceURL returns the URL of the first URL action of a service, or a
+// generic URL built from the service type.
+func serviceURL(cfg actions.Config, it data.ListItem) string {
+	for _, a := range cfg.For(it) {
+		if a.URL == "" {
+			continue
+		}
+		if url, err := a.ExpandURL(it); err == nil {
+			return url
+		}
+	}
+	scheme, _, _ := strings.Cut(strings.TrimPrefix(it.Service, "_"), ".")
+	return scheme + "://" + hostPort(it)
+}
+
+// hostPort returns host:port using the host name, or the address if the
+// service has no host name.
+func hostPort(it data.ListItem) string {
+	host := strings.TrimSuffix(it.Host, ".")
+	if host == "" {
+		return health.Address(it)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(it.Port))
+}
+
+// copySelected copies a field of the selected item and reports the result
+// in the status bar.
+func (m model) copySelected(what string) tea.Cmd {
+	it, ok := m.list.SelectedItem().(data.ListItem)
+	if !ok {
+		return nil
+	}
+
+	var text string
+	switch what {
+	case "address":
+		text = it.AddrV4
+		if text == "" {
+			text = it.AddrV6
+		}
+	case "host:port":
+		text = hostPort(it)
+	case "URL":
+		text = serviceURL(m.actions, it)
+	case "JSON":
+		b, err := json.MarshalIndent(it, "", "  ")
+		if err != nil {
+			return m.list.NewStatusMessage("copy failed: " + err.Error())
+		}
+		text = string(b)
+	}
+	if text == "" {
+		return m.list.NewStatusMessage("nothing to copy, no " + what)
+	}
+
+	if err := copyToClipboard(text); err != nil {
+		return m.list.NewStatusMessage("copy failed: " + err.Error())
+	}
+	if strings.Contains(text, "\n") {
+		return m.list.NewStatusMessage("copied " + what)
+	}
+	return m.list.NewStatusMessage("copied " + text)
+}
@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/query"
+	"slices"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// filterTable maps the filter values of the list rows to the services a
+// query is matched against: a service, all services of a host, or one
+// service per record of a packet. Group headers and statistics are not in
+// the table and never match. The list filters in a command, so the table
+// is shared by copies of the model and guarded by a mutex.
+type filterTable struct {
+	mu       sync.RWMutex
+	services map[string][]data.ListItem
+}
+
+// set replaces the table with the services of rows
+func (t *filterTable) set(rows []list.Item) {
+	services := make(map[string][]data.ListItem, len(rows))
+	for _, row := range rows {
+		switch it := row.(type) {
+		case data.ListItem:
+			services[it.FilterValue()] = []data.ListItem{it}
+		case hostItem:
+			services[it.FilterValue()] = it.Services
+		case packetItem:
+			services[it.FilterValue()] = it.services()
+		}
+	}
+	t.mu.Lock()
+	t.services = services
+	t.mu.Unlock()
+}
+
+// filter is a list.FilterFunc that evaluates the filter as a query against
+// the services of each row, which matches if any of them does. Nothing
+// matches while the query is invalid, e.g. while a quote has not been
+// closed yet.
+func (t *filterTable) filter(term string, targets []string) []list.Rank {
+	q, err := query.Parse(term)
+	if err != nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var ranks []list.Rank
+	for i, target := range targets {
+		if slices.ContainsFunc(t.services[target], q.Match) {
+			ranks = append(ranks, list.Rank{Index: i})
+		}
+	}
+	return ranks
+}
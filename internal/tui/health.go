@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"context"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/health"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// message carrying the health check results of a service
+type healthMsg struct {
+	id      string
+	results []data.CheckResult
+	cert    *data.CertInfo
+}
+
+// command that runs the health checks of a service in the background,
+// including a certificate inspection for services that use TLS
+func runHealthChecks(it data.ListItem) tea.Cmd {
+	return func() tea.Msg {
+		msg := healthMsg{id: it.ID(), results: health.Check(context.Background(), it)}
+		if health.IsSecure(it.Service) && it.Port > 0 {
+			msg.cert = health.InspectCertificate(context.Background(), it)
+		}
+		return msg
+	}
+}
+
+// checkSelected runs the health checks of the selected item. Unless force
+// is set, items that have been checked before are skipped.
+func (m model) checkSelected(force bool) tea.Cmd {
+	it, ok := m.list.SelectedItem().(data.ListItem)
+	if !ok || !force && m.checked[it.ID()] {
+		return nil
+	}
+	m.checked[it.ID()] = true
+	return runHealthChecks(it)
+}
+
+// setHealth stores health check results on the matching service.
+func (m *model) setHealth(msg healthMsg) tea.Cmd {
+	idx := slices.IndexFunc(m.items, func(it data.ListItem) bool { return it.ID() == msg.id })
+	if idx == -1 {
+		return nil
+	}
+	m.items[idx].Health = msg.results
+	m.items[idx].Certificate = msg.cert
+	cmd := m.rebuild()
+	if sel, ok := m.list.SelectedItem().(data.ListItem); ok && sel.ID() == msg.id {
+		m.vp.SetContent(m.serviceDetails(m.items[idx]))
+	}
+	return cmd
+}
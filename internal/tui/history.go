@@ -0,0 +1,106 @@
+package tui
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/history"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxIntervals is the number of presence intervals shown in the history
+const maxIntervals = 10
+
+// historyMsg carries the recorded sightings of a service
+type historyMsg struct {
+	id        string
+	sightings []history.Sighting
+	err       error
+}
+
+// loadHistory reads the sightings of the service from the history store
+// if the history is shown. The sightings are read again only when another
+// service is selected or the history is toggled, not on every response.
+func (m *model) loadHistory(it data.ListItem) tea.Cmd {
+	if !m.showHistory || m.history == nil || m.historyID == it.ID() {
+		return nil
+	}
+	m.historyID = it.ID()
+	store := m.history
+	return func() tea.Msg {
+		sightings, err := store.Sightings(it.Name)
+		return historyMsg{id: it.ID(), sightings: sightings, err: err}
+	}
+}
+
+// historySection describes the presence timeline of a service from its
+// recorded sightings
+func historySection(sightings []history.Sighting) data.Section {
+	section := data.Section{Title: "📜 History"}
+	if len(sightings) == 0 {
+		section.Text = "No sightings recorded yet."
+		return section
+	}
+
+	intervals := history.Presence(sightings, history.DefaultGap)
+	last := sightings[len(sightings)-1]
+	changes := 0
+	for i := 1; i < len(sightings); i++ {
+		if sightings[i].TXTHash != sightings[i-1].TXTHash {
+			changes++
+		}
+	}
+	section.Fields = []data.Field{
+		{Label: "First Seen", Value: intervals[0].Start.Local().Format(time.DateTime)},
+		{Label: "Last Seen", Value: last.Time.Local().Format(time.DateTime)},
+		{Label: "Sightings", Value: fmt.Sprintf("%d", len(sightings))},
+		{Label: "Online", Value: fmt.Sprintf("%d times", len(intervals))},
+		{Label: "TXT Changes", Value: fmt.Sprintf("%d", changes)},
+	}
+
+	slices.Reverse(intervals)
+	for _, i := range intervals[:min(len(intervals), maxIntervals)] {
+		item := fmt.Sprintf("%s – %s (%s)", i.Start.Local().Format(time.DateTime), i.End.Local().Format(time.TimeOnly), i.End.Sub(i.Start).Round(time.Minute))
+		if i.Interface != "" {
+			item += " on " + i.Interface
+		}
+		section.Items = append(section.Items, item)
+	}
+	return section
+}
+
+// serviceDetails renders the details of a service with its presence
+// during the session, and its history if the history is shown. On the
+// records tab it renders the resource records of the service instead.
+func (m model) serviceDetails(it data.ListItem) string {
+	if m.detailsTab == detailsRecords {
+		return m.recordsDetails(it)
+	}
+	sections := it.Sections()
+	if m.activity[it.ID()].count > 0 {
+		sections = append(sections, m.presenceSection(it))
+	}
+	if m.showHistory && m.history != nil {
+		if sightings, ok := m.sightings[it.ID()]; ok {
+			sections = append(sections, historySection(sightings))
+		} else {
+			sections = append(sections, data.Section{Title: "📜 History", Text: "Loading..."})
+		}
+	}
+	return data.RenderSections(sections, m.vpWidth)
+}
+
+// setHistory stores loaded sightings and shows them if the service is
+// still selected
+func (m *model) setHistory(msg historyMsg) tea.Cmd {
+	if msg.err != nil {
+		return m.list.NewStatusMessage("error reading history: " + msg.err.Error())
+	}
+	m.sightings[msg.id] = msg.sightings
+	if sel, ok := m.list.SelectedItem().(data.ListItem); ok && sel.ID() == msg.id {
+		m.vp.SetContent(m.serviceDetails(sel))
+	}
+	return nil
+}
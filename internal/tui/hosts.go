@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/inventory"
+	"strings"
+	"time"
+)
+
+// hostItem is a row of the hosts tab
+type hostItem struct {
+	inventory.Host
+	firstSeen time.Time
+	lastSeen  time.Time
+	width     int
+}
+
+func (h hostItem) Title() string {
+	return h.Name
+}
+
+func (h hostItem) Description() string {
+	count := fmt.Sprintf("%d services", len(h.Services))
+	if len(h.Services) == 1 {
+		count = "1 service"
+	}
+	var profile []string
+	for _, s := range []string{h.Vendor, h.Model} {
+		if s != "" {
+			profile = append(profile, s)
+		}
+	}
+	if len(profile) == 0 {
+		return count
+	}
+	return strings.Join(profile, " ") + " · " + count
+}
+
+// FilterValue identifies the host in the filter table, so that a host
+// matches a query if any of its services does
+func (h hostItem) FilterValue() string {
+	return rowKey(h)
+}
+
+// Sections describes the device profile of the host
+func (h hostItem) Sections() []data.Section {
+	device := data.Section{Title: "🖥 " + h.Name}
+	for _, f := range []data.Field{
+		{Label: "Model", Value: h.Model},
+		{Label: "Vendor", Value: h.Vendor},
+		{Label: "OS", Value: h.OS},
+		{Label: "Interfaces", Value: strings.Join(h.Interfaces, ", ")},
+		{Label: "MAC Addresses", Value: strings.Join(h.MACs, ", ")},
+	} {
+		if f.Value != "" {
+			device.Fields = append(device.Fields, f)
+		}
+	}
+	if !h.firstSeen.IsZero() {
+		device.Fields = append(device.Fields,
+			data.Field{Label: "First Seen", Value: h.firstSeen.Format(time.DateTime)},
+			data.Field{Label: "Last Seen", Value: h.lastSeen.Format(time.DateTime)},
+		)
+	}
+	sections := []data.Section{device}
+
+	addrs := data.Section{Title: "🌐 Addresses", Items: append(append([]string{}, h.AddrV4...), h.AddrV6...)}
+	if len(addrs.Items) > 0 {
+		sections = append(sections, addrs)
+	}
+
+	services := data.Section{Title: "🧩 Services"}
+	for _, it := range h.Services {
+		name := it.Instance
+		if name == "" {
+			name = it.Name
+		}
+		if it.Port > 0 {
+			name += fmt.Sprintf(" (%s, port %d)", it.Service, it.Port)
+		} else if it.Service != "" {
+			name += " (" + it.Service + ")"
+		}
+		services.Items = append(services.Items, name)
+	}
+	return append(sections, services)
+}
+
+func (h hostItem) Details() string {
+	return data.RenderSections(h.Sections(), h.width)
+}
+
+// hostRows aggregates the services by host and adds when each host was
+// first and last seen
+func (m *model) hostRows(items []data.ListItem) []hostItem {
+	var rows []hostItem
+	for _, h := range inventory.Hosts(items) {
+		row := hostItem{Host: h, width: m.vpWidth}
+		for _, it := range h.Services {
+			s := m.activity[it.ID()]
+			if row.firstSeen.IsZero() || s.first.Before(row.firstSeen) {
+				row.firstSeen = s.first
+			}
+			if s.last.After(row.lastSeen) {
+				row.lastSeen = s.last
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
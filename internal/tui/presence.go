@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"strings"
+	"time"
+)
+
+// maxSamples is the number of announcement times kept per service
+const maxSamples = 1000
+
+// sameResponse is how close sightings of a service are counted as one
+// announcement, as discovery and the traffic capture both see every
+// response that discovery solicits
+const sameResponse = time.Second
+
+// sparks are the levels of the presence sparkline
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// activity records the announcements of a service during the session
+type activity struct {
+	first time.Time
+	last  time.Time
+	count int
+	times []time.Time // most recent announcements, at most maxSamples
+}
+
+// add records an announcement at t
+func (a activity) add(t time.Time) activity {
+	if !a.last.IsZero() && t.Sub(a.last).Abs() < sameResponse {
+		if t.After(a.last) {
+			a.last = t
+		}
+		return a
+	}
+	if a.first.IsZero() || t.Before(a.first) {
+		a.first = t
+	}
+	if t.After(a.last) {
+		a.last = t
+	}
+	a.count++
+	a.times = append(a.times, t)
+	if len(a.times) > maxSamples {
+		a.times = a.times[len(a.times)-maxSamples:]
+	}
+	return a
+}
+
+// sparkline shows the number of announcements in width equal buckets
+// from start to end. Buckets without announcements are shown as dots.
+func (a activity) sparkline(start, end time.Time, width int) string {
+	if width <= 0 || !end.After(start) {
+		return ""
+	}
+	buckets := make([]int, width)
+	bucket := end.Sub(start) / time.Duration(width)
+	for _, t := range a.times {
+		if t.Before(start) {
+			continue
+		}
+		buckets[min(int(t.Sub(start)/max(bucket, 1)), width-1)]++
+	}
+	peak := 0
+	for _, n := range buckets {
+		peak = max(peak, n)
+	}
+
+	var b strings.Builder
+	for _, n := range buckets {
+		if n == 0 {
+			b.WriteRune('·')
+			continue
+		}
+		b.WriteRune(sparks[(n*len(sparks)-1)/peak])
+	}
+	return b.String()
+}
+
+// presenceSection describes when a service was seen during the session
+func (m model) presenceSection(it data.ListItem) data.Section {
+	a := m.activity[it.ID()]
+	section := data.Section{Title: "📈 Presence"}
+	now := time.Now()
+	section.Fields = []data.Field{
+		{Label: "First Seen", Value: a.first.Format(time.TimeOnly)},
+		{Label: "Last Seen", Value: fmt.Sprintf("%s (%s ago)", a.last.Format(time.TimeOnly), now.Sub(a.last).Round(time.Second))},
+		{Label: "Announcements", Value: fmt.Sprintf("%d", a.count)},
+	}
+	// hashicorp/mdns does not expose TTLs, so for multicast responses the
+	// TTL is only known from the traffic capture
+	ttl, expires := it.TTL, a.last.Add(time.Duration(it.TTL)*time.Second)
+	if r, exp, ok := m.records.Lookup(it.Name, "SRV"); ok {
+		ttl, expires = r.TTL, exp
+	}
+	if ttl > 0 {
+		value := "expired"
+		if remaining := expires.Sub(now); remaining > 0 {
+			value = fmt.Sprintf("%s of %ds", remaining.Round(time.Second), ttl)
+		}
+		section.Fields = append(section.Fields, data.Field{Label: "TTL Remaining", Value: value})
+	}
+
+	// announcements over the whole session, oldest on the left
+	section.Fields = append(section.Fields, data.Field{Label: "Session", Value: now.Sub(m.started).Round(time.Second).String()})
+	section.Text = a.sparkline(m.started, now, max(10, min(60, m.vpWidth-8)))
+	return section
+}
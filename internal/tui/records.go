@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/discovery"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// detailsTab selects what the details view shows for a service
+type detailsTab int
+
+const (
+	detailsInfo detailsTab = iota
+	detailsRecords
+)
+
+var detailsTabNames = []string{"Details", "Records"}
+
+// detailsTabsView renders the tab bar of the details view, which is only
+// shown while a service is selected
+func (m model) detailsTabsView() string {
+	if _, ok := m.list.SelectedItem().(data.ListItem); !ok {
+		return ""
+	}
+	activeStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#7D56F4")).
+		Underline(true)
+	inactiveStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#666666"))
+
+	var tabs []string
+	for i, name := range detailsTabNames {
+		style := inactiveStyle
+		if detailsTab(i) == m.detailsTab {
+			style = activeStyle
+		}
+		tabs = append(tabs, style.Render(name))
+	}
+	return strings.Join(tabs, inactiveStyle.Render(" │ "))
+}
+
+// recordsDetails lists every resource record the service was resolved
+// from, with escaped names next to their unescaped form
+func (m model) recordsDetails(it data.ListItem) string {
+	records := m.records.For(it)
+	summary := data.Section{Title: "🧾 Records"}
+	switch {
+	case len(records) == 0:
+		summary.Text = "No records captured yet. hashicorp/mdns does not expose the records of multicast responses, so they are taken from the traffic capture as responses arrive."
+	case len(records) == 1:
+		summary.Text = "1 record"
+	default:
+		summary.Text = fmt.Sprintf("%d records", len(records))
+	}
+	sections := []data.Section{summary}
+
+	for _, r := range records {
+		section := data.Section{Title: r.Type, Fields: []data.Field{{Label: "Owner", Value: r.Name}}}
+		if unescaped := discovery.UnescapeDNSName(r.Name); unescaped != r.Name {
+			section.Fields = append(section.Fields, data.Field{Label: "Owner (unescaped)", Value: unescaped})
+		}
+		cacheFlush := "no"
+		if r.CacheFlush {
+			cacheFlush = "yes"
+		}
+		section.Fields = append(section.Fields,
+			data.Field{Label: "TTL", Value: fmt.Sprintf("%ds", r.TTL)},
+			data.Field{Label: "Class", Value: r.Class},
+			data.Field{Label: "Cache Flush", Value: cacheFlush},
+			data.Field{Label: "Data", Value: r.Data},
+		)
+		if r.Type == "PTR" {
+			if unescaped := discovery.UnescapeDNSName(r.Data); unescaped != r.Data {
+				section.Fields = append(section.Fields, data.Field{Label: "Data (unescaped)", Value: unescaped})
+			}
+		}
+		sections = append(sections, section)
+	}
+	return data.RenderSections(sections, m.vpWidth)
+}
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"cmp"
+	"mdns-browser/internal/data"
+	"net/netip"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sortBy selects the order of the service list
+type sortBy int
+
+const (
+	sortFirstSeen sortBy = iota
+	sortLastSeen
+	sortName
+	sortType
+	sortHost
+	sortAddr
+	sortPort
+)
+
+func (s sortBy) String() string {
+	switch s {
+	case sortLastSeen:
+		return "last seen"
+	case sortName:
+		return "name"
+	case sortType:
+		return "type"
+	case sortHost:
+		return "host"
+	case sortAddr:
+		return "IP"
+	case sortPort:
+		return "port"
+	}
+	return "first seen"
+}
+
+// addr returns the IPv4 address of a service, or the IPv6 address if it
+// has none
+func addr(it data.ListItem) (netip.Addr, bool) {
+	if a, err := netip.ParseAddr(it.AddrV4); err == nil {
+		return a, true
+	}
+	if a, err := netip.ParseAddr(it.AddrV6); err == nil {
+		return a, true
+	}
+	return netip.Addr{}, false
+}
+
+// compare orders two services by the sort mode. Services without an
+// address sort after all others.
+func (m *model) compare(a, b data.ListItem) int {
+	switch m.sortBy {
+	case sortLastSeen:
+		// most recent first
+		return m.activity[b.ID()].last.Compare(m.activity[a.ID()].last)
+	case sortName:
+		return strings.Compare(a.ID(), b.ID())
+	case sortType:
+		return strings.Compare(strings.ToLower(a.Service), strings.ToLower(b.Service))
+	case sortHost:
+		return strings.Compare(strings.ToLower(a.Host), strings.ToLower(b.Host))
+	case sortAddr:
+		addrA, okA := addr(a)
+		addrB, okB := addr(b)
+		if !okA || !okB {
+			// true sorts after false
+			return cmp.Compare(boolInt(!okA), boolInt(!okB))
+		}
+		return addrA.Compare(addrB)
+	case sortPort:
+		return cmp.Compare(a.Port, b.Port)
+	}
+	return m.activity[a.ID()].first.Compare(m.activity[b.ID()].first)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// sorted returns the services in the current sort order. Ties keep the
+// order in which the services were first received, so rows do not jump
+// around as new services arrive.
+func (m *model) sorted() []data.ListItem {
+	items := slices.Clone(m.items)
+	slices.SortStableFunc(items, m.compare)
+	return items
+}
+
+// cycleSortBy switches to the next sort mode
+func (m *model) cycleSortBy() tea.Cmd {
+	m.sortBy = (m.sortBy + 1) % (sortPort + 1)
+	m.updateTitle()
+	return m.rebuild()
+}
+
+// updateTitle shows the current tab or sort mode in the list title
+func (m *model) updateTitle() {
+	switch m.tab {
+	case tabHosts:
+		m.list.Title = "Hosts"
+		return
+	case tabTraffic:
+		m.list.Title = "Traffic"
+		return
+	case tabStats:
+		m.list.Title = "Statistics"
+		return
+	}
+	m.list.Title = m.title + " (sorted by " + m.sortBy.String() + ")"
+}
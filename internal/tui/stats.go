@@ -0,0 +1,253 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/inventory"
+	"mdns-browser/internal/traffic"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// rateWindow is the period packet rates are averaged over
+	rateWindow = 10 * time.Second
+
+	// maxLatencies is the number of response latencies kept per type
+	maxLatencies = 1000
+
+	// topTypes is the number of service types in the top list
+	topTypes = 10
+)
+
+// statsTickMsg refreshes the statistics tab
+type statsTickMsg time.Time
+
+func statsTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return statsTickMsg(t) })
+}
+
+// packetStats counts the captured packets received from other hosts and
+// sent by this host
+type packetStats struct {
+	in, out             int
+	recentIn, recentOut []time.Time // packets within rateWindow
+}
+
+// add counts a packet
+func (s *packetStats) add(p traffic.Packet) {
+	if p.Src == "" {
+		// not a packet but an error of the capture
+		return
+	}
+	if p.Local {
+		s.out++
+		s.recentOut = append(s.recentOut, p.Time)
+	} else {
+		s.in++
+		s.recentIn = append(s.recentIn, p.Time)
+	}
+	s.prune(p.Time)
+}
+
+// prune drops the recent packets older than rateWindow
+func (s *packetStats) prune(now time.Time) {
+	old := func(t time.Time) bool { return now.Sub(t) > rateWindow }
+	s.recentIn = slices.DeleteFunc(s.recentIn, old)
+	s.recentOut = slices.DeleteFunc(s.recentOut, old)
+}
+
+// rates returns the packets per second received and sent
+func (s *packetStats) rates(now time.Time) (in, out float64) {
+	s.prune(now)
+	return float64(len(s.recentIn)) / rateWindow.Seconds(), float64(len(s.recentOut)) / rateWindow.Seconds()
+}
+
+// statsItem is a row of the statistics tab
+type statsItem struct {
+	title    string
+	summary  string
+	sections []data.Section
+	width    int
+}
+
+func (s statsItem) Title() string {
+	return s.title
+}
+
+func (s statsItem) Description() string {
+	return s.summary
+}
+
+// FilterValue is empty as statistics never match a query
+func (s statsItem) FilterValue() string {
+	return ""
+}
+
+func (s statsItem) Details() string {
+	return data.RenderSections(s.sections, s.width)
+}
+
+// count is a number of services by key
+type count struct {
+	key string
+	n   int
+}
+
+// countBy counts the items by key, most frequent first
+func countBy(items []data.ListItem, key func(data.ListItem) string) []count {
+	var counts []count
+	for _, it := range items {
+		k := key(it)
+		if k == "" {
+			k = "(unknown)"
+		}
+		idx := slices.IndexFunc(counts, func(c count) bool { return c.key == k })
+		if idx == -1 {
+			counts = append(counts, count{key: k})
+			idx = len(counts) - 1
+		}
+		counts[idx].n++
+	}
+	slices.SortFunc(counts, func(a, b count) int { return cmp.Or(cmp.Compare(b.n, a.n), strings.Compare(a.key, b.key)) })
+	return counts
+}
+
+// bar renders n relative to most as a bar of at most width cells
+func bar(n, most, width int) string {
+	if most == 0 {
+		return ""
+	}
+	return strings.Repeat("█", max(1, n*width/most))
+}
+
+// countSection lists counts with bars
+func countSection(title string, counts []count, width int) data.Section {
+	section := data.Section{Title: title}
+	if len(counts) == 0 {
+		section.Text = "None yet."
+		return section
+	}
+	for _, c := range counts {
+		section.Items = append(section.Items, fmt.Sprintf("%s %d %s", c.key, c.n, bar(c.n, counts[0].n, width)))
+	}
+	return section
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	return sorted[((len(sorted)-1)*p+50)/100]
+}
+
+// formatLatency rounds a latency to a readable precision
+func formatLatency(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(10 * time.Millisecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// addLatency keeps the response latency of a service by type
+func (m *model) addLatency(it data.ListItem) {
+	if it.Latency <= 0 {
+		return
+	}
+	k := strings.ToLower(it.Service)
+	samples := append(m.latencies[k], it.Latency)
+	if len(samples) > maxLatencies {
+		samples = samples[len(samples)-maxLatencies:]
+	}
+	m.latencies[k] = samples
+}
+
+// statsRows returns the rows of the statistics tab
+func (m *model) statsRows() []statsItem {
+	now := time.Now()
+	barWidth := max(5, m.vpWidth/3)
+	items := m.items
+	hosts := inventory.Hosts(items)
+	types := countBy(items, func(it data.ListItem) string { return strings.ToLower(it.Service) })
+	ifaces := countBy(items, func(it data.ListItem) string { return it.Interface })
+
+	overview := statsItem{
+		title:   "Overview",
+		summary: fmt.Sprintf("%d services · %d hosts · %d types", len(items), len(hosts), len(types)),
+		sections: []data.Section{{Title: "📊 Overview", Fields: []data.Field{
+			{Label: "Services", Value: fmt.Sprintf("%d", len(items))},
+			{Label: "Hosts", Value: fmt.Sprintf("%d", len(hosts))},
+			{Label: "Service Types", Value: fmt.Sprintf("%d", len(types))},
+			{Label: "Interfaces", Value: fmt.Sprintf("%d", len(ifaces))},
+			{Label: "Session", Value: now.Sub(m.started).Round(time.Second).String()},
+		}}},
+	}
+
+	in, out := m.packetStats.rates(now)
+	packets := statsItem{
+		title:   "Traffic",
+		summary: fmt.Sprintf("%.1f packets/s in · %.1f packets/s out", in, out),
+		sections: []data.Section{{Title: "📶 Traffic", Fields: []data.Field{
+			{Label: "In", Value: fmt.Sprintf("%.1f packets/s", in)},
+			{Label: "Out", Value: fmt.Sprintf("%.1f packets/s", out)},
+			{Label: "Received", Value: fmt.Sprintf("%d packets", m.packetStats.in)},
+			{Label: "Sent", Value: fmt.Sprintf("%d packets", m.packetStats.out)},
+		}, Text: fmt.Sprintf("Rates are averaged over the last %s of the traffic capture. Out counts packets sent from the addresses of this host.", rateWindow)}},
+	}
+
+	top := types[:min(len(types), topTypes)]
+	var topNames []string
+	for _, c := range top[:min(len(top), 3)] {
+		topNames = append(topNames, fmt.Sprintf("%s (%d)", c.key, c.n))
+	}
+	topItem := statsItem{
+		title:    "Top Service Types",
+		summary:  cmp.Or(strings.Join(topNames, ", "), "none yet"),
+		sections: []data.Section{countSection("🏆 Top Service Types", top, barWidth)},
+	}
+
+	latency := statsItem{title: "Response Latency", sections: []data.Section{{Title: "⏱ Response Latency"}}}
+	var all []time.Duration
+	var latencyTypes []string
+	for k, samples := range m.latencies {
+		all = append(all, samples...)
+		latencyTypes = append(latencyTypes, k)
+	}
+	slices.Sort(latencyTypes)
+	if len(all) == 0 {
+		latency.summary = "no responses yet"
+		latency.sections[0].Text = "None yet."
+	} else {
+		slices.Sort(all)
+		latency.summary = fmt.Sprintf("median %s of %d responses", formatLatency(percentile(all, 50)), len(all))
+		latency.sections[0].Fields = []data.Field{
+			{Label: "Median", Value: formatLatency(percentile(all, 50))},
+			{Label: "90th Percentile", Value: formatLatency(percentile(all, 90))},
+			{Label: "Maximum", Value: formatLatency(all[len(all)-1])},
+		}
+		latency.sections[0].Text = "Time from the query to each response. Median, 90th percentile and maximum by type:"
+		for _, k := range latencyTypes {
+			samples := slices.Sorted(slices.Values(m.latencies[k]))
+			latency.sections[0].Items = append(latency.sections[0].Items, fmt.Sprintf("%s %s · %s · %s (%d)",
+				k, formatLatency(percentile(samples, 50)), formatLatency(percentile(samples, 90)), formatLatency(samples[len(samples)-1]), len(samples)))
+		}
+	}
+
+	var ifaceNames []string
+	for _, c := range ifaces {
+		ifaceNames = append(ifaceNames, fmt.Sprintf("%s (%d)", c.key, c.n))
+	}
+	interfaces := statsItem{
+		title:    "Interfaces",
+		summary:  cmp.Or(strings.Join(ifaceNames, ", "), "none yet"),
+		sections: []data.Section{countSection("🔌 Services per Interface", ifaces, barWidth)},
+	}
+
+	rows := []statsItem{overview, packets, topItem, latency, interfaces}
+	for i := range rows {
+		rows[i].width = m.vpWidth
+	}
+	return rows
+}
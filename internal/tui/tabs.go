@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tab selects what the list shows
+type tab int
+
+const (
+	tabServices tab = iota
+	tabHosts
+	tabTraffic
+	tabStats
+)
+
+var tabNames = []string{"Services", "Hosts", "Traffic", "Statistics"}
+
+// tabsView renders the tab bar with the active tab highlighted
+func (m model) tabsView() string {
+	activeStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#7D56F4")).
+		Underline(true)
+	inactiveStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#666666"))
+
+	var tabs []string
+	for i, name := range tabNames {
+		style := inactiveStyle
+		if tab(i) == m.tab {
+			style = activeStyle
+		}
+		tabs = append(tabs, style.Render(fmt.Sprintf("%d %s", i+1, name)))
+	}
+	return " " + strings.Join(tabs, inactiveStyle.Render(" │ "))
+}
@@ -0,0 +1,223 @@
+package tui
+
+import (
+	"fmt"
+	"mdns-browser/internal/data"
+	"mdns-browser/internal/discovery"
+	"mdns-browser/internal/traffic"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// maxPackets is the number of packets kept in the traffic tab
+	maxPackets = 500
+
+	// packetRefresh is how often the traffic tab and the records of the
+	// selected service are updated while packets arrive
+	packetRefresh = 200 * time.Millisecond
+)
+
+// message carrying a decoded packet
+type packetMsg traffic.Packet
+
+// packetRefreshMsg shows the packets received since the last refresh
+type packetRefreshMsg struct{}
+
+// command that waits for the next packet from a channel
+func listenForPackets(ch <-chan traffic.Packet) tea.Cmd {
+	if ch == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		p, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return packetMsg(p)
+	}
+}
+
+// packetItem is a row of the traffic tab
+type packetItem struct {
+	traffic.Packet
+	seq   int // position in the session, identifies the row
+	width int
+}
+
+func (p packetItem) Title() string {
+	return p.Time.Format("15:04:05.000") + " " + p.Src
+}
+
+func (p packetItem) Description() string {
+	kind := "query"
+	if p.Response {
+		kind = "response"
+	}
+	parts := []string{kind}
+	if p.Interface != "" {
+		parts = append(parts, p.Interface)
+	}
+	if p.Err != "" {
+		return strings.Join(append(parts, "⚠ "+p.Err), " · ")
+	}
+	var counts []string
+	for _, section := range []string{"question", "answer", "authority", "additional"} {
+		n := 0
+		for _, r := range p.Records {
+			if r.Section == section {
+				n++
+			}
+		}
+		switch {
+		case n == 1:
+			counts = append(counts, "1 "+section)
+		case n > 1 && section == "authority":
+			counts = append(counts, fmt.Sprintf("%d authorities", n))
+		case n > 1:
+			counts = append(counts, fmt.Sprintf("%d %ss", n, section))
+		}
+	}
+	return strings.Join(append(parts, strings.Join(counts, ", ")), " · ")
+}
+
+// FilterValue identifies the packet in the filter table
+func (p packetItem) FilterValue() string {
+	return rowKey(p)
+}
+
+// services returns one service per record, so that queries on the name,
+// address or interface match packets with any such record
+func (p packetItem) services() []data.ListItem {
+	host, _, _ := net.SplitHostPort(p.Src)
+	var services []data.ListItem
+	for _, r := range p.Records {
+		it := data.ListItem{Name: r.Name, Interface: p.Interface}
+		if strings.Contains(host, ":") {
+			it.AddrV6 = host
+		} else {
+			it.AddrV4 = host
+		}
+		services = append(services, it)
+	}
+	return services
+}
+
+// Sections lists every record of the packet by section
+func (p packetItem) Sections() []data.Section {
+	kind := "Query"
+	if p.Response {
+		kind = "Response"
+	}
+	packet := data.Section{Title: "📡 " + kind, Fields: []data.Field{
+		{Label: "Time", Value: p.Time.Format("2006-01-02 15:04:05.000")},
+		{Label: "Source", Value: p.Src},
+		{Label: "Interface", Value: p.Interface},
+		{Label: "Error", Value: p.Err},
+	}}
+	sections := []data.Section{packet}
+
+	for _, s := range []struct{ name, title string }{
+		{"question", "❓ Questions"},
+		{"answer", "📨 Answers"},
+		{"authority", "🏛 Authority"},
+		{"additional", "➕ Additional"},
+	} {
+		section := data.Section{Title: s.title}
+		for _, r := range p.Records {
+			if r.Section == s.name {
+				section.Items = append(section.Items, formatRecord(r))
+			}
+		}
+		if len(section.Items) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+func (p packetItem) Details() string {
+	return data.RenderSections(p.Sections(), p.width)
+}
+
+// formatRecord formats a record like a zone file line. The cache-flush
+// bit of questions asks for a unicast response.
+func formatRecord(r traffic.Record) string {
+	fields := []string{r.Name}
+	if r.Section != "question" {
+		fields = append(fields, fmt.Sprintf("%d", r.TTL))
+	}
+	fields = append(fields, r.Class, r.Type)
+	if r.Data != "" {
+		fields = append(fields, strings.TrimSpace(r.Data))
+	}
+	switch {
+	case r.CacheFlush && r.Section == "question":
+		fields = append(fields, "(unicast response)")
+	case r.CacheFlush:
+		fields = append(fields, "(cache flush)")
+	}
+	return strings.Join(fields, " ")
+}
+
+// addPacket appends a packet to the traffic log and keeps its records.
+// Every SRV record in a response counts as an announcement of its service,
+// as the capture sees announcements that discovery does not query for.
+// The views are refreshed with all packets received within packetRefresh,
+// so that bursts of packets do not rebuild the list for every packet.
+func (m *model) addPacket(p traffic.Packet) tea.Cmd {
+	m.records.Add(p)
+	m.packetStats.add(p)
+	if p.Response {
+		for _, r := range p.Records {
+			if r.Section != "question" && r.Type == "SRV" && r.TTL > 0 {
+				id := strings.ToLower(discovery.UnescapeDNSName(r.Name))
+				m.activity[id] = m.activity[id].add(p.Time)
+			}
+		}
+	}
+	m.packetSeq++
+	m.packets = append(m.packets, packetItem{Packet: p, seq: m.packetSeq})
+	if len(m.packets) > maxPackets {
+		m.packets = m.packets[len(m.packets)-maxPackets:]
+	}
+	if m.refreshDue {
+		return nil
+	}
+	m.refreshDue = true
+	return tea.Tick(packetRefresh, func(time.Time) tea.Msg { return packetRefreshMsg{} })
+}
+
+// refreshPackets shows the packets and records received since the last
+// refresh. While the last packet is selected, the selection follows new
+// packets.
+func (m *model) refreshPackets() tea.Cmd {
+	m.refreshDue = false
+	if it, ok := m.list.SelectedItem().(data.ListItem); ok && m.detailsTab == detailsRecords {
+		m.vp.SetContent(m.serviceDetails(it))
+	}
+	if m.tab != tabTraffic {
+		return nil
+	}
+	follow := len(m.list.Items()) == 0 || m.list.Index() == len(m.list.Items())-1
+	cmd := m.rebuild()
+	if follow && m.list.FilterState() == list.Unfiltered {
+		m.list.Select(len(m.list.Items()) - 1)
+		return tea.Batch(cmd, m.showSelected())
+	}
+	return cmd
+}
+
+// packetRows returns the rows of the traffic tab, oldest first
+func (m *model) packetRows() []packetItem {
+	rows := make([]packetItem, len(m.packets))
+	for i, p := range m.packets {
+		p.width = m.vpWidth
+		rows[i] = p
+	}
+	return rows
+}
@@ -0,0 +1,215 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mdns-browser/internal/data"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// groupBy selects how the service list is grouped
+type groupBy int
+
+const (
+	groupNone groupBy = iota
+	groupHost
+	groupType
+	groupInterface
+)
+
+func (g groupBy) String() string {
+	switch g {
+	case groupHost:
+		return "host"
+	case groupType:
+		return "type"
+	case groupInterface:
+		return "interface"
+	}
+	return "none"
+}
+
+// key returns the group a service belongs to
+func (g groupBy) key(it data.ListItem) string {
+	var k string
+	switch g {
+	case groupHost:
+		k = strings.ToLower(it.Host)
+	case groupType:
+		k = strings.ToLower(it.Service)
+	case groupInterface:
+		k = it.Interface
+	}
+	if k == "" {
+		return "(unknown)"
+	}
+	return k
+}
+
+// groupItem is a collapsible group header in the service list
+type groupItem struct {
+	key       string
+	by        groupBy
+	services  []data.ListItem
+	collapsed bool
+}
+
+func (g groupItem) Title() string {
+	if g.collapsed {
+		return "▸ " + g.key
+	}
+	return "▾ " + g.key
+}
+
+func (g groupItem) Description() string {
+	if len(g.services) == 1 {
+		return "1 service"
+	}
+	return fmt.Sprintf("%d services", len(g.services))
+}
+
+// FilterValue is empty as groups are hidden while the list is filtered
+func (g groupItem) FilterValue() string {
+	return ""
+}
+
+// Details summarizes the group for the details view
+func (g groupItem) Details() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#7D56F4")).
+		MarginBottom(1)
+	bulletStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#888888"))
+
+	details := []string{titleStyle.Render("📂 " + g.key), g.Description(), ""}
+	for _, it := range g.services {
+		name := it.Name
+		if strings.TrimSpace(name) == "" {
+			name = it.Host
+		}
+		details = append(details, bulletStyle.Render("• ")+name)
+	}
+	return strings.Join(details, "\n")
+}
+
+// rowKey identifies a list row across rebuilds
+func rowKey(item list.Item) string {
+	switch it := item.(type) {
+	case data.ListItem:
+		return "service:" + it.ID()
+	case groupItem:
+		return "group:" + it.key
+	case hostItem:
+		return "host:" + it.Name
+	case packetItem:
+		return fmt.Sprintf("packet:%d", it.seq)
+	case statsItem:
+		return "stats:" + it.title
+	}
+	return ""
+}
+
+// treeDelegate renders group headers like services and indents the
+// services below them while the list is grouped
+type treeDelegate struct {
+	list.DefaultDelegate
+	grouped bool
+}
+
+func (d treeDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if _, ok := item.(data.ListItem); !ok || !d.grouped {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+	var b bytes.Buffer
+	d.DefaultDelegate.Render(&b, m, index, item)
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	fmt.Fprint(w, strings.Join(lines, "\n"))
+}
+
+// rebuild recreates the list rows of the current tab from all services or
+// packets, keeping the selection on the same row
+func (m *model) rebuild() tea.Cmd {
+	selected := rowKey(m.list.SelectedItem())
+
+	items := m.sorted()
+	var rows []list.Item
+	switch {
+	case m.tab == tabStats:
+		for _, s := range m.statsRows() {
+			rows = append(rows, s)
+		}
+	case m.tab == tabTraffic:
+		for _, p := range m.packetRows() {
+			rows = append(rows, p)
+		}
+	case m.tab == tabHosts:
+		for _, h := range m.hostRows(items) {
+			rows = append(rows, h)
+		}
+	case m.groupBy == groupNone:
+		for _, it := range items {
+			rows = append(rows, it)
+		}
+	default:
+		var groups []groupItem
+		for _, it := range items {
+			k := m.groupBy.key(it)
+			idx := slices.IndexFunc(groups, func(g groupItem) bool { return g.key == k })
+			if idx == -1 {
+				groups = append(groups, groupItem{key: k, by: m.groupBy, collapsed: m.collapsed[m.groupBy.String()+":"+k]})
+				idx = len(groups) - 1
+			}
+			groups[idx].services = append(groups[idx].services, it)
+		}
+		slices.SortFunc(groups, func(a, b groupItem) int { return strings.Compare(a.key, b.key) })
+		for _, g := range groups {
+			rows = append(rows, g)
+			if !g.collapsed {
+				for _, it := range g.services {
+					rows = append(rows, it)
+				}
+			}
+		}
+	}
+
+	m.filters.set(rows)
+	cmd := m.list.SetItems(rows)
+	for i, row := range rows {
+		if rowKey(row) == selected {
+			m.list.Select(i)
+			break
+		}
+	}
+	return cmd
+}
+
+// cycleGroupBy switches to the next grouping mode
+func (m *model) cycleGroupBy() tea.Cmd {
+	m.groupBy = (m.groupBy + 1) % (groupInterface + 1)
+	m.list.SetDelegate(treeDelegate{DefaultDelegate: list.NewDefaultDelegate(), grouped: m.groupBy != groupNone})
+	cmd := m.rebuild()
+	return tea.Batch(cmd, m.showSelected(), m.list.NewStatusMessage("grouped by "+m.groupBy.String()))
+}
+
+// toggleGroup collapses or expands the selected group. It reports false
+// if no group is selected.
+func (m *model) toggleGroup() (tea.Cmd, bool) {
+	g, ok := m.list.SelectedItem().(groupItem)
+	if !ok {
+		return nil, false
+	}
+	k := g.by.String() + ":" + g.key
+	m.collapsed[k] = !m.collapsed[k]
+	return m.rebuild(), true
+}
@@ -1,9 +1,16 @@
 package tui
 
 import (
+	"fmt"
+	"mdns-browser/internal/actions"
 	"mdns-browser/internal/data"
+	"mdns-browser/internal/export"
+	"mdns-browser/internal/history"
+	"mdns-browser/internal/traffic"
+	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
@@ -17,26 +24,62 @@ import (
 var docStyle = lipgloss.NewStyle().Margin(1, 2)
 
 type ListOpts struct {
-	Title string
-	AddCh chan data.ListItem
+	Title        string
+	AddCh        chan data.ListItem
+	ExportOrigin string                // Origin of zone files written by the export key
+	Actions      actions.Config        // Launch actions, in addition to the defaults
+	History      *history.Store        // Store of past sightings, optional
+	Packets      <-chan traffic.Packet // Decoded mDNS packets for the traffic tab, optional
 }
 
+// exportFile is the file the export key writes the list to.
+const exportFile = "mdns-browser.zone"
+
 // message carrying a new ListItem
 type addItemMsg data.ListItem
 
 // command that waits for the next ListItem from a channel
 func listenForItems(ch <-chan data.ListItem) tea.Cmd {
 	return func() tea.Msg {
-		it := <-ch
+		it, ok := <-ch
+		if !ok {
+			// discovery finished, stop listening
+			return nil
+		}
 		return addItemMsg(it)
 	}
 }
 
 type model struct {
+	items        []data.ListItem     // all services in arrival order
+	activity     map[string]activity // announcements of services by ID
+	started      time.Time
+	tab          tab
+	sortBy       sortBy
+	groupBy      groupBy
+	collapsed    map[string]bool // collapsed groups by mode and key
+	title        string
 	list         list.Model
+	filters      *filterTable // services of the rows for the list filter
 	vp           viewport.Model
 	help         help.Model
 	addCh        chan data.ListItem
+	exportOrigin string
+	checked      map[string]bool // items whose health checks have been started
+	actions      actions.Config
+	menu         *actionMenu // open action menu, if any
+	history      *history.Store
+	sightings    map[string][]history.Sighting // loaded history by service
+	historyID    string                        // service whose history was loaded last
+	showHistory  bool
+	packets      []packetItem // most recent packets, at most maxPackets
+	packetSeq    int
+	packetCh     <-chan traffic.Packet
+	refreshDue   bool               // a refresh of the packets is scheduled
+	records      *traffic.RecordSet // resource records from captured responses
+	detailsTab   detailsTab
+	packetStats  *packetStats
+	latencies    map[string][]time.Duration // response latencies by service type
 	spinnerTick  tea.Cmd
 	listWidth    int
 	vpWidth      int
@@ -50,11 +93,26 @@ type keyMap struct {
 	Quit       key.Binding
 	Tab        key.Binding
 	HelpToggle key.Binding
+	SwitchTab  key.Binding
 
 	// List-specific keys
-	Up    key.Binding
-	Down  key.Binding
-	Slash key.Binding
+	Up      key.Binding
+	Down    key.Binding
+	Slash   key.Binding
+	Export  key.Binding
+	Check   key.Binding
+	Launch  key.Binding
+	Sort    key.Binding
+	Group   key.Binding
+	Toggle  key.Binding
+	History key.Binding
+	Records key.Binding
+
+	// Clipboard keys
+	CopyAddr     key.Binding
+	CopyHostPort key.Binding
+	CopyURL      key.Binding
+	CopyJSON     key.Binding
 
 	// Viewport-specific keys
 	ScrollUp   key.Binding
@@ -83,13 +141,17 @@ func (k keyMap) ShortHelp() []key.Binding {
 
 // FullHelp returns keybindings for the expanded help view
 func (k keyMap) FullHelp() [][]key.Binding {
-	commonKeys := []key.Binding{k.Quit, k.Tab, k.HelpToggle}
+	commonKeys := []key.Binding{k.Quit, k.Tab, k.HelpToggle, k.Check}
+	copyKeys := []key.Binding{k.CopyAddr, k.CopyHostPort, k.CopyURL, k.CopyJSON}
 
 	// List-specific keys
 	if len(k.Up.Keys()) > 0 {
 		return [][]key.Binding{
 			commonKeys,
-			{k.Up, k.Down, k.Slash},
+			{k.Up, k.Down, k.Slash, k.SwitchTab},
+			{k.Export, k.Launch, k.Sort},
+			{k.Group, k.Toggle, k.History, k.Records},
+			copyKeys,
 		}
 	}
 
@@ -98,7 +160,8 @@ func (k keyMap) FullHelp() [][]key.Binding {
 		return [][]key.Binding{
 			commonKeys,
 			{k.ScrollUp, k.ScrollDown, k.PageUp, k.PageDown},
-			{k.GoToTop, k.GoToBottom},
+			{k.GoToTop, k.GoToBottom, k.History, k.Records},
+			copyKeys,
 		}
 	}
 
@@ -118,6 +181,10 @@ var keys = keyMap{
 		key.WithKeys("?"),
 		key.WithHelp("?", "toggle help"),
 	),
+	SwitchTab: key.NewBinding(
+		key.WithKeys("1", "2", "3", "4"),
+		key.WithHelp("1-4", "switch tab"),
+	),
 	Up: key.NewBinding(
 		key.WithKeys("k", "up"),
 		key.WithHelp("↑/k", "move up"),
@@ -130,6 +197,54 @@ var keys = keyMap{
 		key.WithKeys("/"),
 		key.WithHelp("/", "filter list"),
 	),
+	Export: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "export zone file"),
+	),
+	Check: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "run health checks"),
+	),
+	Launch: key.NewBinding(
+		key.WithKeys("enter", "o"),
+		key.WithHelp("enter/o", "actions"),
+	),
+	Sort: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "change sort order"),
+	),
+	Group: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "change grouping"),
+	),
+	Toggle: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "collapse/expand group"),
+	),
+	History: key.NewBinding(
+		key.WithKeys("H"),
+		key.WithHelp("H", "toggle history"),
+	),
+	Records: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "details/records"),
+	),
+	CopyAddr: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "copy address"),
+	),
+	CopyHostPort: key.NewBinding(
+		key.WithKeys("Y"),
+		key.WithHelp("Y", "copy host:port"),
+	),
+	CopyURL: key.NewBinding(
+		key.WithKeys("U"),
+		key.WithHelp("U", "copy URL"),
+	),
+	CopyJSON: key.NewBinding(
+		key.WithKeys("J"),
+		key.WithHelp("J", "copy JSON"),
+	),
 	ScrollUp: key.NewBinding(
 		key.WithKeys("k", "up"),
 		key.WithHelp("↑/k", "scroll up"),
@@ -156,39 +271,70 @@ var keys = keyMap{
 	),
 }
 
+// historyKey returns the history key binding if a history store is set
+func (m model) historyKey() key.Binding {
+	if m.history == nil {
+		return key.Binding{}
+	}
+	return keys.History
+}
+
 // contextualKeyMap creates a keyMap based on the current focused view
 func (m model) contextualKeyMap() keyMap {
 	if m.focusedView == 0 { // list focused
 		return keyMap{
-			Quit:       keys.Quit,
-			Tab:        keys.Tab,
-			HelpToggle: keys.HelpToggle,
-			Up:         keys.Up,
-			Down:       keys.Down,
-			Slash:      keys.Slash,
+			Quit:         keys.Quit,
+			Tab:          keys.Tab,
+			HelpToggle:   keys.HelpToggle,
+			Up:           keys.Up,
+			Down:         keys.Down,
+			Slash:        keys.Slash,
+			SwitchTab:    keys.SwitchTab,
+			Export:       keys.Export,
+			Check:        keys.Check,
+			Launch:       keys.Launch,
+			Sort:         keys.Sort,
+			Group:        keys.Group,
+			Toggle:       keys.Toggle,
+			History:      m.historyKey(),
+			Records:      keys.Records,
+			CopyAddr:     keys.CopyAddr,
+			CopyHostPort: keys.CopyHostPort,
+			CopyURL:      keys.CopyURL,
+			CopyJSON:     keys.CopyJSON,
 		}
 	} else { // viewport focused
 		return keyMap{
-			Quit:       keys.Quit,
-			Tab:        keys.Tab,
-			HelpToggle: keys.HelpToggle,
-			ScrollUp:   keys.ScrollUp,
-			ScrollDown: keys.ScrollDown,
-			PageUp:     keys.PageUp,
-			PageDown:   keys.PageDown,
-			GoToTop:    keys.GoToTop,
-			GoToBottom: keys.GoToBottom,
+			Quit:         keys.Quit,
+			Tab:          keys.Tab,
+			HelpToggle:   keys.HelpToggle,
+			ScrollUp:     keys.ScrollUp,
+			ScrollDown:   keys.ScrollDown,
+			PageUp:       keys.PageUp,
+			PageDown:     keys.PageDown,
+			GoToTop:      keys.GoToTop,
+			GoToBottom:   keys.GoToBottom,
+			History:      m.historyKey(),
+			Records:      keys.Records,
+			Check:        keys.Check,
+			CopyAddr:     keys.CopyAddr,
+			CopyHostPort: keys.CopyHostPort,
+			CopyURL:      keys.CopyURL,
+			CopyJSON:     keys.CopyJSON,
 		}
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(listenForItems(m.addCh), m.spinnerTick)
+	return tea.Batch(listenForItems(m.addCh), listenForPackets(m.packetCh), m.spinnerTick, statsTick())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.menu != nil {
+			return m.updateMenu(msg)
+		}
 		k := msg.String()
 		switch k {
 		case "ctrl+c", "q":
@@ -234,30 +380,97 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.vp.GotoBottom()
 				return m, nil
 			}
+		case "e":
+			if m.focusedView == 0 && m.list.FilterState() != list.Filtering {
+				return m, m.list.NewStatusMessage(m.exportZone())
+			}
+		case "enter", "o", " ":
+			if m.focusedView == 0 && m.list.FilterState() != list.Filtering {
+				if cmd, ok := m.toggleGroup(); ok {
+					return m, cmd
+				}
+				if k == " " {
+					return m, nil
+				}
+				return m.openMenu()
+			}
+		case "1", "2", "3", "4":
+			if m.list.FilterState() != list.Filtering {
+				return m, m.switchTab(tab(k[0] - '1'))
+			}
+		case "s":
+			if m.tab == tabServices && m.focusedView == 0 && m.list.FilterState() != list.Filtering {
+				return m, m.cycleSortBy()
+			}
+		case "v":
+			if m.tab == tabServices && m.focusedView == 0 && m.list.FilterState() != list.Filtering {
+				return m, m.cycleGroupBy()
+			}
+		case "H":
+			if m.history != nil && m.list.FilterState() != list.Filtering {
+				m.showHistory = !m.showHistory
+				m.historyID = ""
+				return m, m.showSelected()
+			}
+		case "R":
+			if m.list.FilterState() != list.Filtering {
+				m.detailsTab = (m.detailsTab + 1) % (detailsRecords + 1)
+				m.vp.GotoTop()
+				return m, m.showSelected()
+			}
+		case "y", "Y", "U", "J":
+			if m.list.FilterState() != list.Filtering {
+				what := map[string]string{"y": "address", "Y": "host:port", "U": "URL", "J": "JSON"}[k]
+				return m, m.copySelected(what)
+			}
+		case "r":
+			if m.list.FilterState() != list.Filtering {
+				if cmd := m.checkSelected(true); cmd != nil {
+					return m, tea.Batch(cmd, m.list.NewStatusMessage("running health checks…"))
+				}
+				return m, nil
+			}
 		}
+	case healthMsg:
+		return m, m.setHealth(msg)
+	case historyMsg:
+		return m, m.setHistory(msg)
+	case statsTickMsg:
+		if m.tab != tabStats {
+			return m, statsTick()
+		}
+		return m, tea.Batch(statsTick(), m.rebuild(), m.showSelected())
+	case packetRefreshMsg:
+		return m, m.refreshPackets()
+	case packetMsg:
+		return m, tea.Batch(listenForPackets(m.packetCh), m.addPacket(traffic.Packet(msg)))
+	case actionDoneMsg:
+		if msg.err != nil {
+			return m, m.list.NewStatusMessage(msg.name + " failed: " + msg.err.Error())
+		}
+		return m, nil
 	case tea.WindowSizeMsg:
 		h, v := docStyle.GetFrameSize()
 		totalWidth := msg.Width - h
 
-		// Reserve space for help at the bottom
+		// Reserve space for the tabs at the top and help at the bottom
+		tabsHeight := 1
 		helpHeight := 3
-		availableHeight := msg.Height - v - helpHeight
+		availableHeight := msg.Height - v - tabsHeight - helpHeight
+		// and for the tabs of the details view
+		detailsTabsHeight := 1
 
 		m.listWidth = totalWidth * 2 / 3
 		m.vpWidth = totalWidth / 3
 		m.list.SetSize(m.listWidth, availableHeight)
 		m.vp.Width = m.vpWidth
-		m.vp.Height = availableHeight
+		m.vp.Height = availableHeight - detailsTabsHeight
 		m.help.Width = msg.Width
-		for _, item := range m.list.Items() {
-			li, ok := item.(data.ListItem)
-			if !ok {
-				continue
-			}
-			li.MaxListWidth = m.listWidth
-			li.MaxDetailsWidth = m.vpWidth
+		for i := range m.items {
+			m.items[i].MaxListWidth = m.listWidth
+			m.items[i].MaxDetailsWidth = m.vpWidth
 		}
-		return m, nil
+		return m, m.rebuild()
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.list, cmd = m.list.Update(msg)
@@ -266,23 +479,28 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		listItem := data.ListItem(msg)
 		listItem.MaxListWidth = m.listWidth
 		listItem.MaxDetailsWidth = m.vpWidth
-		currentItems := m.list.Items()
-		idx := slices.IndexFunc(currentItems, func(it list.Item) bool {
-			li, ok := it.(data.ListItem)
-			if !ok {
-				return false
-			}
-			return strings.EqualFold(li.Name, listItem.Name)
+		seenAt := listItem.LastSeen
+		if seenAt.IsZero() {
+			seenAt = time.Now()
+		}
+		m.activity[listItem.ID()] = m.activity[listItem.ID()].add(seenAt)
+		m.addLatency(listItem)
+		idx := slices.IndexFunc(m.items, func(it data.ListItem) bool {
+			return strings.EqualFold(it.Name, listItem.Name)
 		})
-		numberOfItems := len(currentItems)
-		if idx == -1 {
-			if numberOfItems == 0 {
-				m.vp.SetContent(listItem.Details())
-			}
-			m.list.InsertItem(numberOfItems, listItem)
+		if idx != -1 {
+			// keep the results of checks run for the previous state
+			listItem.Health = m.items[idx].Health
+			listItem.Certificate = m.items[idx].Certificate
+			m.items[idx] = listItem
+		} else {
+			m.items = append(m.items, listItem)
 		}
+		// the selected row may be the new service or a host or group
+		// containing it
+		cmd := m.rebuild()
 		// keep listening
-		return m, listenForItems(m.addCh)
+		return m, tea.Batch(listenForItems(m.addCh), cmd, m.showSelected())
 	}
 
 	var cmd tea.Cmd
@@ -294,18 +512,68 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Update viewport content when list selection changes
 		if m.list.Index() != oldIndex && len(m.list.Items()) > 0 {
-			if selectedItem := m.list.SelectedItem(); selectedItem != nil {
-				if listItem, ok := selectedItem.(data.ListItem); ok {
-					m.vp.SetContent(listItem.Details())
-					m.vp.GotoTop() // Reset the scroll position when switching items
-				}
-			}
+			cmd = tea.Batch(cmd, m.showSelected())
+			m.vp.GotoTop() // Reset the scroll position when switching items
 		}
 	}
 
 	return m, cmd
 }
 
+// showSelected shows the selected service or group in the details view
+// and starts the health checks of services that have not been checked.
+func (m *model) showSelected() tea.Cmd {
+	switch it := m.list.SelectedItem().(type) {
+	case data.ListItem:
+		m.vp.SetContent(m.serviceDetails(it))
+		return tea.Batch(m.checkSelected(false), m.loadHistory(it))
+	case groupItem:
+		m.vp.SetContent(it.Details())
+	case hostItem:
+		m.vp.SetContent(it.Details())
+	case packetItem:
+		m.vp.SetContent(it.Details())
+	case statsItem:
+		m.vp.SetContent(it.Details())
+	}
+	return nil
+}
+
+// switchTab shows another tab in the list
+func (m *model) switchTab(t tab) tea.Cmd {
+	if t == m.tab {
+		return nil
+	}
+	m.tab = t
+	m.list.SetDelegate(treeDelegate{DefaultDelegate: list.NewDefaultDelegate(), grouped: t == tabServices && m.groupBy != groupNone})
+	m.updateTitle()
+	cmd := m.rebuild()
+	if t == tabTraffic {
+		// follow new packets
+		m.list.Select(len(m.list.Items()) - 1)
+	} else {
+		m.list.Select(0)
+	}
+	m.vp.GotoTop()
+	return tea.Batch(cmd, m.showSelected())
+}
+
+// exportZone writes all list items to exportFile and returns a status
+// message describing the result.
+func (m model) exportZone() string {
+	items := m.items
+
+	f, err := os.Create(exportFile)
+	if err != nil {
+		return "export failed: " + err.Error()
+	}
+	defer f.Close()
+	if err := export.Zone(f, items, m.exportOrigin); err != nil {
+		return "export failed: " + err.Error()
+	}
+	return fmt.Sprintf("exported %d services to %s", len(items), exportFile)
+}
+
 func (m model) View() string {
 	// Style focused and unfocused views differently
 	listStyle := lipgloss.NewStyle().Width(m.listWidth)
@@ -324,22 +592,28 @@ func (m model) View() string {
 	}
 
 	listView := listStyle.Render(m.list.View())
-	vpView := vpStyle.Render(m.vp.View())
+	vpContent := m.detailsTabsView() + "\n" + m.vp.View()
+	if m.menu != nil {
+		vpContent = lipgloss.NewStyle().Height(m.vp.Height + 1).Render(m.menu.View())
+	}
+	vpView := vpStyle.Render(vpContent)
 	mainView := lipgloss.JoinHorizontal(lipgloss.Top, listView, vpView)
 
 	// Create a contextual help view
 	contextualKeys := m.contextualKeyMap()
 	helpView := m.help.View(contextualKeys)
 
-	return lipgloss.JoinVertical(lipgloss.Left, mainView, helpView)
+	return lipgloss.JoinVertical(lipgloss.Left, m.tabsView(), mainView, helpView)
 }
 
 func Tui(opts ListOpts) tea.Model {
 	var items []list.Item
-	listDelegate := list.NewDefaultDelegate()
+	listDelegate := treeDelegate{DefaultDelegate: list.NewDefaultDelegate()}
 	l := list.New(items, listDelegate, 0, 0)
 	l.Styles.TitleBar.PaddingLeft(5)
 	l.SetSpinner(spinner.MiniDot)
+	filters := &filterTable{}
+	l.Filter = filters.filter
 
 	// Disable the built-in help for the list since we'll handle it ourselves
 	l.SetShowHelp(false)
@@ -351,9 +625,27 @@ func Tui(opts ListOpts) tea.Model {
 	h := help.New()
 	h.ShowAll = true // Start with full help to show more keys
 
+	if opts.ExportOrigin == "" {
+		opts.ExportOrigin = "local."
+	}
+
 	m := model{
 		list:         l,
+		filters:      filters,
 		addCh:        opts.AddCh,
+		exportOrigin: opts.ExportOrigin,
+		checked:      make(map[string]bool),
+		collapsed:    make(map[string]bool),
+		activity:     make(map[string]activity),
+		started:      time.Now(),
+		title:        opts.Title,
+		actions:      opts.Actions,
+		history:      opts.History,
+		packetCh:     opts.Packets,
+		records:      traffic.NewRecordSet(),
+		packetStats:  &packetStats{},
+		latencies:    make(map[string][]time.Duration),
+		sightings:    make(map[string][]history.Sighting),
 		spinnerTick:  tick,
 		vp:           vp,
 		help:         h,
@@ -361,6 +653,6 @@ func Tui(opts ListOpts) tea.Model {
 		showFullHelp: true, // Start with full help
 	}
 
-	m.list.Title = opts.Title
+	m.updateTitle()
 	return m
 }
@@ -0,0 +1,173 @@
+"use strict";
+
+// services holds the live set of services keyed by id, in arrival order.
+const services = new Map();
+let selected = null;
+
+const listEl = document.getElementById("services");
+const detailsEl = document.getElementById("details");
+const filterEl = document.getElementById("filter");
+const countEl = document.getElementById("count");
+const statusEl = document.getElementById("status");
+
+function serviceId(service) {
+  return service.name.toLowerCase();
+}
+
+function title(service) {
+  return service.name.trim() === "" ? service.host : service.name;
+}
+
+// matches reports whether all characters of the query appear in order in
+// the text, similar to the fuzzy filter of the TUI.
+function matches(query, text) {
+  query = query.toLowerCase();
+  text = text.toLowerCase();
+  let pos = 0;
+  for (const ch of query) {
+    pos = text.indexOf(ch, pos);
+    if (pos === -1) {
+      return false;
+    }
+    pos++;
+  }
+  return true;
+}
+
+function filterText(service) {
+  return [service.name, service.service, service.domain, service.host,
+    service.addrV4, service.addrV6, service.port, service.info].join(" ");
+}
+
+function renderList() {
+  const query = filterEl.value.trim();
+  listEl.replaceChildren();
+  let shown = 0;
+  for (const [id, service] of services) {
+    if (query !== "" && !matches(query, filterText(service))) {
+      continue;
+    }
+    shown++;
+    const li = document.createElement("li");
+    li.dataset.id = id;
+    if (id === selected) {
+      li.classList.add("selected");
+    }
+    const t = document.createElement("span");
+    t.className = "title";
+    t.textContent = title(service);
+    const d = document.createElement("span");
+    d.className = "desc";
+    d.textContent = service.host;
+    li.append(t, d);
+    li.addEventListener("click", () => select(id));
+    listEl.append(li);
+  }
+  countEl.textContent = query === "" ? `(${services.size})` : `(${shown}/${services.size})`;
+}
+
+async function renderDetails() {
+  if (selected === null || !services.has(selected)) {
+    detailsEl.innerHTML = '<p class="empty">No service selected.</p>';
+    return;
+  }
+  const resp = await fetch("details/" + encodeURIComponent(selected));
+  if (!resp.ok) {
+    return;
+  }
+  const sections = await resp.json();
+  detailsEl.replaceChildren();
+  sections.forEach((section, n) => {
+    const h = document.createElement(n === 0 ? "h2" : "h3");
+    h.textContent = section.title;
+    detailsEl.append(h);
+    if (section.fields) {
+      const dl = document.createElement("dl");
+      for (const field of section.fields) {
+        const dt = document.createElement("dt");
+        dt.textContent = field.label + ":";
+        const dd = document.createElement("dd");
+        dd.textContent = field.value;
+        dl.append(dt, dd);
+      }
+      detailsEl.append(dl);
+    }
+    if (section.text) {
+      const p = document.createElement("p");
+      p.textContent = section.text;
+      detailsEl.append(p);
+    }
+    if (section.items) {
+      const ul = document.createElement("ul");
+      for (const item of section.items) {
+        const li = document.createElement("li");
+        li.textContent = item;
+        ul.append(li);
+      }
+      detailsEl.append(ul);
+    }
+  });
+}
+
+function select(id) {
+  selected = id;
+  renderList();
+  renderDetails();
+}
+
+function connect() {
+  const events = new EventSource("api/events");
+  events.onopen = () => {
+    services.clear();
+    statusEl.textContent = "live";
+  };
+  events.onerror = () => {
+    statusEl.textContent = "reconnecting…";
+  };
+  const onChange = (e) => {
+    const ev = JSON.parse(e.data);
+    const id = serviceId(ev.service);
+    if (ev.kind === "removed") {
+      services.delete(id);
+    } else {
+      services.set(id, ev.service);
+    }
+    if (selected === null && services.size > 0) {
+      selected = services.keys().next().value;
+    }
+    renderList();
+    if (id === selected) {
+      renderDetails();
+    }
+  };
+  for (const kind of ["added", "updated", "removed"]) {
+    events.addEventListener(kind, onChange);
+  }
+}
+
+filterEl.addEventListener("input", renderList);
+
+document.addEventListener("keydown", (e) => {
+  if (e.target === filterEl) {
+    if (e.key === "Escape") {
+      filterEl.value = "";
+      filterEl.blur();
+      renderList();
+    }
+    return;
+  }
+  const ids = [...listEl.children].map((li) => li.dataset.id);
+  const idx = ids.indexOf(selected);
+  if ((e.key === "j" || e.key === "ArrowDown") && idx < ids.length - 1) {
+    select(ids[idx + 1]);
+    e.preventDefault();
+  } else if ((e.key === "k" || e.key === "ArrowUp") && idx > 0) {
+    select(ids[idx - 1]);
+    e.preventDefault();
+  } else if (e.key === "/") {
+    filterEl.focus();
+    e.preventDefault();
+  }
+});
+
+connect();
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"mdns-browser/internal/api"
+	"mdns-browser/internal/cache"
+	"net/http"
+	"time"
+)
+
+//go:embed static
+var static embed.FS
+
+// Server serves the single-page web UI together with the HTTP API, which
+// is mounted under /api/.
+type Server struct {
+	Cache   *cache.Cache
+	Metrics http.Handler // Served on /metrics and /api/metrics when set
+
+	// AllowedOrigins are passed on to the API, see api.Server.
+	AllowedOrigins []string
+}
+
+// Handler returns the routes of the web UI.
+func (s *Server) Handler() http.Handler {
+	assets, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(assets))
+	mux.Handle("GET /api/", http.StripPrefix("/api", (&api.Server{Cache: s.Cache, Metrics: s.Metrics, AllowedOrigins: s.AllowedOrigins}).Handler()))
+	if s.Metrics != nil {
+		mux.Handle("GET /metrics", s.Metrics)
+	}
+	mux.HandleFunc("GET /details/{id...}", s.details)
+	return mux
+}
+
+// details returns the sections of the details view of a service, so the
+// browser renders the same fields as the TUI.
+func (s *Server) details(w http.ResponseWriter, r *http.Request) {
+	it, ok := s.Cache.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "service not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(it.Sections())
+}
+
+// ListenAndServe serves the web UI on addr until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}